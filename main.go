@@ -2,12 +2,16 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"log"
 	"math/rand"
 	"net/http"
+	"os"
 	"runtime"
+	"strconv"
 	"strings"
 	"time"
 	"trollfish/uci"
@@ -59,16 +63,262 @@ func createDownloadScripts() {
 	}
 }
 
-func main() {
+// perft prints the divide counts for a position, e.g.
+// trollfish perft "r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1" 3
+func perft(args []string) {
+	if len(args) < 2 {
+		log.Fatal("usage: trollfish perft <fen> <depth>")
+	}
+
+	fen := strings.Join(args[:len(args)-1], " ")
+	depth, err := strconv.Atoi(args[len(args)-1])
+	if err != nil {
+		log.Fatalf("depth '%s' invalid", args[len(args)-1])
+	}
+
+	b, err := uci.ParseFEN(fen)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	start := time.Now()
+	var nodes int
+	for _, r := range b.Divide(depth) {
+		nodes += r.Nodes
+		fmt.Println(r.String())
+	}
+	fmt.Printf("\nNodes searched: %d (%v)\n", nodes, time.Since(start))
+}
+
+// epd plays each position of an EPD test suite through the proxy and reports
+// how often the troll picks the best move or a move to avoid, e.g.
+// trollfish epd wac.epd
+func epd(args []string) {
+	if len(args) != 1 {
+		log.Fatal("usage: trollfish epd <file>")
+	}
+
+	fp, err := os.Open(args[0])
+	if err != nil {
+		log.Fatal(err)
+	}
+	positions, err := uci.ReadEPD(fp)
+	_ = fp.Close()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var bm, bmTotal, am, amTotal int
+	report := func(r uci.EPDResult) {
+		fmt.Println(r.String())
+		if len(r.EPD.BestMoves) != 0 {
+			bmTotal++
+			if r.Best() {
+				bm++
+			}
+		}
+		if len(r.EPD.AvoidMoves) != 0 {
+			amTotal++
+			if r.Avoided() {
+				am++
+			}
+		}
+	}
+
 	rand.Seed(time.Now().UnixNano())
 
-	p := uci.New("trollfish 15", "the trollfish developers",
+	if _, err := newUCI().RunEPD(context.Background(), positions, report); err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Printf("\nbm: %d/%d (%s) am: %d/%d (%s)\n", bm, bmTotal, percent(bm, bmTotal), am, amTotal, percent(am, amTotal))
+}
+
+func percent(n, total int) string {
+	if total == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%0.1f%%", float64(n)*100/float64(total))
+}
+
+// book builds an opening book from PGN files, e.g.
+// trollfish book build -min-elo 2500 -ply 16 -format polyglot -o troll.bin caissabase.pgn
+func book(args []string) {
+	if len(args) == 0 || args[0] != "build" {
+		log.Fatal("usage: trollfish book build [flags] <pgn files>")
+	}
+
+	fs := flag.NewFlagSet("book build", flag.ExitOnError)
+	minElo := fs.Int("min-elo", 0, "lowest rating of both players, 0 for any")
+	results := fs.String("results", "", "comma separated results to use, e.g. '1-0,0-1'; empty for any")
+	maxPly := fs.Int("ply", 20, "plies of each game to use, 0 for all")
+	minGames := fs.Int("min-games", 1, "games a move must be played in")
+	format := fs.String("format", "polyglot", "book format, 'polyglot' or 'repertoire'")
+	out := fs.String("o", "", "output file")
+	_ = fs.Parse(args[1:])
+
+	if *out == "" || fs.NArg() == 0 {
+		log.Fatal("usage: trollfish book build [flags] -o <book> <pgn files>")
+	}
+	if *format != "polyglot" && *format != "repertoire" {
+		log.Fatalf("format '%s' should be 'polyglot' or 'repertoire'", *format)
+	}
+
+	filter := uci.BookFilter{MinRating: *minElo, MaxPly: *maxPly, MinGames: *minGames}
+	if *results != "" {
+		filter.Results = strings.Split(*results, ",")
+	}
+	bb := uci.NewBookBuilder(filter)
+
+	for _, name := range fs.Args() {
+		fp, err := os.Open(name)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		r := uci.NewPGNReader(fp)
+		for {
+			g, err := r.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil && r.Err() != nil {
+				log.Fatalf("%s: %v", name, err)
+			}
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "%s: %v\n", name, err)
+				continue
+			}
+			bb.AddGame(g)
+		}
+		_ = fp.Close()
+	}
+
+	fp, err := os.Create(*out)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *format == "polyglot" {
+		err = bb.Polyglot().Write(fp)
+	} else {
+		err = bb.WriteRepertoire(fp)
+	}
+	if closeErr := fp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// the first moves, to check the score percentages quoted in firstMoveMap
+	start := uci.FENtoBoard("rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1")
+	fmt.Printf("games: %d skipped: %d\n\n", bb.Games, bb.Skipped)
+	fmt.Println("move    games  score")
+	for _, s := range bb.Stats(&start) {
+		fmt.Printf("%-5s %7d %5.1f%%\n", s.Move, s.Games(), s.Score())
+	}
+}
+
+// calibrateFirstMoves has Stockfish evaluate every first move, and every reply
+// to each of them, and prints the tables for firstMoveMap and firstReplyMap.
+func calibrateFirstMoves(args []string) {
+	fs := flag.NewFlagSet("calibrate-first-moves", flag.ExitOnError)
+	minDepth := fs.Int("min-depth", 35, "first depth to average")
+	maxDepth := fs.Int("max-depth", 50, "last depth to average")
+	threads := fs.Int("threads", runtime.NumCPU(), "Stockfish threads")
+	replies := fs.Bool("replies", true, "calibrate Black's replies to each first move too")
+	_ = fs.Parse(args)
+
+	if *minDepth < 1 || *maxDepth < *minDepth {
+		log.Fatalf("depths %d-%d invalid", *minDepth, *maxDepth)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sf, err := uci.StartStockfish(ctx, func(s string) {})
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer sf.Quit()
+
+	sf.Write("uci")
+	sf.Write(fmt.Sprintf("setoption name Threads value %d", *threads))
+
+	start := uci.FENtoBoard("rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1")
+	evals, err := uci.Calibrate(ctx, sf, start, *minDepth, *maxDepth)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Println("var firstMoveMap = []*firstMove{")
+	if err := uci.WriteCalibration(os.Stdout, start, evals, *minDepth, *maxDepth, "\t"); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println("}")
+
+	if !*replies {
+		return
+	}
+
+	fmt.Println()
+	fmt.Println("var firstReplyMap = map[string][]*firstMove{")
+	for _, first := range evals {
+		b := start
+		b.Moves(first.Move)
+
+		replyEvals, err := uci.Calibrate(ctx, sf, b, *minDepth, *maxDepth)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		fmt.Printf("\t%q: {\n", first.Move)
+		if err := uci.WriteCalibration(os.Stdout, b, replyEvals, *minDepth, *maxDepth, "\t\t"); err != nil {
+			log.Fatal(err)
+		}
+		fmt.Println("\t},")
+	}
+	fmt.Println("}")
+}
+
+func newUCI() *uci.UCI {
+	return uci.New("trollfish 15", "the trollfish developers",
 		uci.Option{Name: "Threads", Type: uci.OptionTypeSpin, Default: "1", Min: 1, Max: runtime.NumCPU()},
 		uci.Option{Name: "MultiPV", Type: uci.OptionTypeString, Default: "8"},
 		uci.Option{Name: "PlayBad", Type: uci.OptionTypeString, Default: "false"},
 		uci.Option{Name: "StartAgro", Type: uci.OptionTypeString, Default: "false"},
 		uci.Option{Name: "SyzygyPath", Type: uci.OptionTypeString, Default: ""},
+		uci.Option{Name: "BookFile", Type: uci.OptionTypeString, Default: ""},
+		uci.Option{Name: "Repertoire", Type: uci.OptionTypeString, Default: ""},
+		uci.Option{Name: "FreezeBookLearning", Type: uci.OptionTypeCheck, Default: "false"},
+		uci.Option{Name: "BookEvalFloor", Type: uci.OptionTypeSpin, Default: "-300", Min: -10000, Max: 10000},
+		uci.Option{Name: "UCI_Chess960", Type: uci.OptionTypeCheck, Default: "false"},
+		uci.Option{Name: "UCI_Opponent", Type: uci.OptionTypeString, Default: ""},
 	)
+}
+
+func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "perft":
+			perft(os.Args[2:])
+			return
+		case "epd":
+			epd(os.Args[2:])
+			return
+		case "book":
+			book(os.Args[2:])
+			return
+		case "calibrate-first-moves":
+			calibrateFirstMoves(os.Args[2:])
+			return
+		}
+		// anything else is from a GUI or wrapper, and is ignored
+	}
+
+	rand.Seed(time.Now().UnixNano())
+
+	p := newUCI()
 	ctx, _ := p.Start(context.Background())
 	<-ctx.Done()
 }
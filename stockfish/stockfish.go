@@ -13,9 +13,9 @@ import (
 )
 
 type StockFish struct {
-	Ctx    context.Context
-	Output <-chan string
+	Ctx context.Context
 
+	output  <-chan string
 	cancel  context.CancelFunc
 	writer  io.WriteCloser
 	logInfo func(string)
@@ -33,7 +33,7 @@ func Start(ctx context.Context, binary string, logInfo func(string)) (*StockFish
 
 	var sf StockFish
 	sf.Ctx, sf.cancel = context.WithCancel(ctx)
-	sf.Output = output
+	sf.output = output
 	sf.logInfo = logInfo
 
 	cmd := exec.CommandContext(sf.Ctx, binary)
@@ -115,6 +115,11 @@ func (sf *StockFish) Write(s string) {
 	_, _ = sf.writer.Write(b)
 }
 
+// Output returns Stockfish's stdout, one line at a time.
+func (sf *StockFish) Output() <-chan string {
+	return sf.output
+}
+
 func (sf *StockFish) Quit() {
 	sf.cancel()
 }
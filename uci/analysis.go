@@ -0,0 +1,172 @@
+package uci
+
+import (
+	"fmt"
+	"strings"
+)
+
+// pieceValues is indexed by PieceType, in centipawns. The king's value only
+// matters in exchanges, where capturing into a defended square with it must
+// never pay.
+var pieceValues = [7]int{NoPieceType: 0, Pawn: 100, Knight: 300, Bishop: 300, Rook: 500, Queen: 900, King: 20_000}
+
+// Value returns the material value of the piece type in centipawns.
+func (t PieceType) Value() int {
+	return pieceValues[t]
+}
+
+// Material returns the value of the color's pieces, not counting the king.
+func (b *Board) Material(c Color) int {
+	var total int
+	for t := Pawn; t < King; t++ {
+		total += b.pieces[makePiece(c, t)].Count() * t.Value()
+	}
+	return total
+}
+
+// MaterialBalance returns the material difference from the side to move's
+// point of view, the same way Stockfish reports scores.
+func (b *Board) MaterialBalance() int {
+	return b.Material(b.ActiveColor) - b.Material(b.ActiveColor.Other())
+}
+
+// Attackers returns the pieces of the color which attack sq.
+func (b *Board) Attackers(sq Square, c Color) Bitboard {
+	return b.attackersTo(sq, b.occupied()) & b.colors[c]
+}
+
+// Defenders returns the pieces defending the piece on sq, or 0 if the square
+// is empty.
+func (b *Board) Defenders(sq Square) Bitboard {
+	p := b.squares[sq]
+	if p == NoPiece {
+		return 0
+	}
+	return b.Attackers(sq, p.Color())
+}
+
+// Hanging returns the color's pieces the opponent wins material by capturing,
+// judged by static exchange. Pins and checks are ignored.
+func (b *Board) Hanging(c Color) Bitboard {
+	var hanging Bitboard
+	for pieces := b.colors[c] &^ b.pieces[makePiece(c, King)]; pieces != 0; pieces &= pieces - 1 {
+		sq := pieces.first()
+		for attackers := b.Attackers(sq, c.Other()); attackers != 0; attackers &= attackers - 1 {
+			if b.SEE(NewMove(attackers.first(), sq, NoPieceType)) > 0 {
+				hanging |= sq.bitboard()
+				break
+			}
+		}
+	}
+	return hanging
+}
+
+// SEE returns the static exchange evaluation of the move: the material the
+// mover wins (or loses, if negative) when both sides keep capturing on the
+// destination square with their least valuable piece for as long as it pays.
+// A quiet move scores 0, or less if the piece can be taken. Pins and checks
+// are ignored.
+func (b *Board) SEE(m Move) int {
+	from, to := m.From(), m.To()
+	piece := b.squares[from]
+	if piece == NoPiece || b.castlingSide(from, to, b.castling) != -1 {
+		return 0
+	}
+
+	occupied := b.occupied() &^ from.bitboard()
+
+	var gain [32]int
+	gain[0] = b.squares[to].Type().Value()
+
+	attacker := piece.Type()
+	if attacker == Pawn && to == b.EnPassantSquare {
+		gain[0] = Pawn.Value()
+		occupied &^= makeSquare(to.File(), from.Rank()).bitboard()
+	}
+	if p := m.Promotion(); p != NoPieceType {
+		gain[0] += p.Value() - Pawn.Value()
+		attacker = p
+	}
+
+	d := 0
+	for side := piece.Color().Other(); d < len(gain)-1; side = side.Other() {
+		attackers := b.attackersTo(to, occupied) & occupied & b.colors[side]
+		if attackers == 0 {
+			break
+		}
+
+		// take with the least valuable piece; removing it from the occupancy
+		// uncovers any slider behind it
+		next := NoPieceType
+		for t := Pawn; t <= King && next == NoPieceType; t++ {
+			if bb := attackers & b.pieces[makePiece(side, t)]; bb != 0 {
+				next = t
+				occupied &^= bb.first().bitboard()
+			}
+		}
+
+		d++
+		gain[d] = attacker.Value() - gain[d-1]
+		attacker = next
+	}
+
+	// either side can stop capturing when carrying on loses material
+	for ; d > 0; d-- {
+		gain[d-1] = -max(-gain[d-1], gain[d])
+	}
+
+	return gain[0]
+}
+
+// MoveAnalysis is the static picture of a move, for telling a quiet move
+// from one which gives material away.
+type MoveAnalysis struct {
+	Move  Move
+	Piece Piece
+
+	// SEE is the static exchange on the destination square, see Board.SEE.
+	SEE int
+
+	// Balance is the material balance after the exchange on the destination
+	// square, from the mover's point of view.
+	Balance int
+
+	// Hanging holds the mover's pieces left en prise after the move.
+	Hanging Bitboard
+}
+
+// AnalyzeMove returns the static analysis of a move for the side to move.
+func (b *Board) AnalyzeMove(m Move) MoveAnalysis {
+	c := b.clone()
+	c.MakeMove(m)
+
+	see := b.SEE(m)
+	return MoveAnalysis{
+		Move:    m,
+		Piece:   b.squares[m.From()],
+		SEE:     see,
+		Balance: b.MaterialBalance() + see,
+		Hanging: c.Hanging(b.ActiveColor),
+	}
+}
+
+// Sacrifice returns the kind of piece the move gives up by static exchange,
+// or NoPieceType if it doesn't lose material.
+func (a MoveAnalysis) Sacrifice() PieceType {
+	if a.SEE >= 0 {
+		return NoPieceType
+	}
+	return a.Piece.Type()
+}
+
+func (a MoveAnalysis) String() string {
+	hanging := "-"
+	if a.Hanging != 0 {
+		var squares []string
+		for _, sq := range a.Hanging.Squares() {
+			squares = append(squares, sq.String())
+		}
+		hanging = strings.Join(squares, ",")
+	}
+	return fmt.Sprintf("see %d balance %d hanging %s", a.SEE, a.Balance, hanging)
+}
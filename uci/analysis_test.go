@@ -0,0 +1,124 @@
+package uci
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMaterialBalance(t *testing.T) {
+	// arrange
+	cases := []struct {
+		fen  string
+		want int
+	}{
+		{fen: startPosFEN, want: 0},
+		{fen: "rnbqkbnr/pppp1ppp/8/4p3/4P3/8/PPPP1PPP/RNBQKBNR w KQkq - 0 2", want: 0},
+		{fen: "4k3/8/8/8/8/8/8/R3K3 w - - 0 1", want: 500},
+		{fen: "4k3/8/8/8/8/8/8/R3K3 b - - 0 1", want: -500},
+		{fen: "4k3/pp6/8/8/8/8/8/N3K3 w - - 0 1", want: 100},
+	}
+
+	for _, c := range cases {
+		t.Run(c.fen, func(t *testing.T) {
+			// act
+			b := FENtoBoard(c.fen)
+			got := b.MaterialBalance()
+
+			// assert
+			if got != c.want {
+				t.Errorf("want: %d got: %d", c.want, got)
+			}
+		})
+	}
+}
+
+func TestSEE(t *testing.T) {
+	// arrange
+	cases := []struct {
+		name string
+		fen  string
+		move string
+		want int
+	}{
+		{name: "undefended pawn", fen: "1k1r4/1pp4p/p7/4p3/8/P5P1/1PP4P/2K1R3 w - - 0 1", move: "e1e5", want: 100},
+		{name: "defended pawn, queen behind the rook", fen: "1k1r3q/1ppn3p/p4b2/4p3/8/P2N2P1/1PP1R1BP/2K1Q3 w - - 0 1", move: "d3e5", want: -200},
+		{name: "pawn takes defended knight", fen: "4k3/8/3p4/4n3/3P4/8/8/4K3 w - - 0 1", move: "d4e5", want: 200},
+		{name: "quiet move", fen: "4k3/8/3p4/8/8/8/8/2N1K3 w - - 0 1", move: "c1b3", want: 0},
+		{name: "knight steps into a pawn", fen: "4k3/8/3p4/8/8/3N4/8/4K3 w - - 0 1", move: "d3e5", want: -300},
+		{name: "defended knight steps into a pawn", fen: "4k3/8/3p4/8/3P4/3N4/8/4K3 w - - 0 1", move: "d3e5", want: -200},
+		{name: "queen takes a defended pawn", fen: "4k3/8/3p4/4p3/8/8/8/4KQ2 w - - 0 1", move: "f1f5", want: 0},
+		{name: "queen walks into a pawn", fen: "4k3/8/3p4/8/8/8/8/4KQ2 w - - 0 1", move: "f1c5", want: -900},
+		{name: "rook battery outnumbers", fen: "3rk3/3r4/8/3p4/8/3R4/3R4/3RK3 w - - 0 1", move: "d2d5", want: 100},
+		{name: "en passant", fen: "4k3/8/8/3pP3/8/8/8/4K3 w - d6 0 2", move: "e5d6", want: 100},
+		{name: "promotion", fen: "4k3/1P6/8/8/8/8/8/4K3 w - - 0 1", move: "b7b8q", want: 800},
+		{name: "king can't take a defended piece", fen: "4k3/8/8/8/8/8/3rr3/4K3 w - - 0 1", move: "e1e2", want: -19_500},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			b := FENtoBoard(c.fen)
+			m, err := ParseMove(c.move)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// act
+			got := b.SEE(m)
+
+			// assert
+			if got != c.want {
+				t.Errorf("want: %d got: %d", c.want, got)
+			}
+		})
+	}
+}
+
+func TestHanging(t *testing.T) {
+	// arrange
+	cases := []struct {
+		name  string
+		fen   string
+		color Color
+		want  string
+	}{
+		{name: "start position", fen: startPosFEN, color: White, want: ""},
+		{name: "undefended knight", fen: "4k3/8/8/3n4/8/8/8/3RK3 b - - 0 1", color: Black, want: "d5"},
+		{name: "defended knight", fen: "4k3/8/4p3/3n4/8/8/8/3RK3 b - - 0 1", color: Black, want: ""},
+		{name: "rook attacked by a bishop", fen: "4k3/8/4p3/3r4/8/8/6B1/4K3 b - - 0 1", color: Black, want: "d5"},
+		{name: "scholar's mate threat", fen: "r1bqkbnr/pppp1ppp/2n5/4p2Q/2B1P3/8/PPPP1PPP/RNB1K1NR b KQkq - 3 3", color: Black, want: "f7"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			b := FENtoBoard(c.fen)
+
+			// act
+			var got []string
+			for _, sq := range b.Hanging(c.color).Squares() {
+				got = append(got, sq.String())
+			}
+
+			// assert
+			if strings.Join(got, " ") != c.want {
+				t.Errorf("want: '%s' got: '%s'", c.want, strings.Join(got, " "))
+			}
+		})
+	}
+}
+
+func TestAnalyzeMove(t *testing.T) {
+	// arrange
+	b := FENtoBoard("r1bqkbnr/pppp1ppp/2n5/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R b KQkq - 3 3")
+	m, _ := ParseMove("d8g5")
+
+	// act
+	a := b.AnalyzeMove(m)
+
+	// assert
+	if a.Sacrifice() != Queen {
+		t.Errorf("Sacrifice, want: %s got: %s", Queen, a.Sacrifice())
+	}
+	if want := "see -900 balance -900 hanging g5"; a.String() != want {
+		t.Errorf("want: '%s' got: '%s'", want, a.String())
+	}
+}
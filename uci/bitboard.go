@@ -0,0 +1,115 @@
+package uci
+
+import "math/bits"
+
+// Bitboard is a set of squares, bit n is set if Square n is in the set.
+type Bitboard uint64
+
+// Count returns the number of squares in the set.
+func (b Bitboard) Count() int {
+	return bits.OnesCount64(uint64(b))
+}
+
+// Has returns true if the square is in the set.
+func (b Bitboard) Has(sq Square) bool {
+	return b&sq.bitboard() != 0
+}
+
+// first returns the lowest square in the set, or NoSquare if it's empty.
+func (b Bitboard) first() Square {
+	if b == 0 {
+		return NoSquare
+	}
+	return Square(bits.TrailingZeros64(uint64(b)))
+}
+
+// last returns the highest square in the set. The set must not be empty.
+func (b Bitboard) last() Square {
+	return Square(63 - bits.LeadingZeros64(uint64(b)))
+}
+
+// Squares returns the squares in the set from a1 to h8.
+func (b Bitboard) Squares() []Square {
+	squares := make([]Square, 0, b.Count())
+	for ; b != 0; b &= b - 1 {
+		squares = append(squares, b.first())
+	}
+	return squares
+}
+
+// ray directions; the first four go up the board (to higher squares)
+const (
+	dirN = iota
+	dirNE
+	dirE
+	dirNW
+	dirS
+	dirSW
+	dirW
+	dirSE
+)
+
+var (
+	rayOffsets = [8][2]int{{0, 1}, {1, 1}, {1, 0}, {-1, 1}, {0, -1}, {-1, -1}, {-1, 0}, {1, -1}}
+
+	knightAttacks [64]Bitboard
+	kingAttacks   [64]Bitboard
+	pawnAttacks   [2][64]Bitboard // by the color of the pawn
+	rays          [8][64]Bitboard // every square in a direction, not including the start
+)
+
+func init() {
+	for sq := Square(0); sq < 64; sq++ {
+		file, rank := sq.File(), sq.Rank()
+
+		for _, o := range knightOffsets {
+			if onBoard(file+o[0], rank+o[1]) {
+				knightAttacks[sq] |= makeSquare(file+o[0], rank+o[1]).bitboard()
+			}
+		}
+		for _, o := range kingOffsets {
+			if onBoard(file+o[0], rank+o[1]) {
+				kingAttacks[sq] |= makeSquare(file+o[0], rank+o[1]).bitboard()
+			}
+		}
+		for _, df := range [2]int{-1, 1} {
+			if onBoard(file+df, rank+1) {
+				pawnAttacks[White][sq] |= makeSquare(file+df, rank+1).bitboard()
+			}
+			if onBoard(file+df, rank-1) {
+				pawnAttacks[Black][sq] |= makeSquare(file+df, rank-1).bitboard()
+			}
+		}
+		for dir, o := range rayOffsets {
+			for f, r := file+o[0], rank+o[1]; onBoard(f, r); f, r = f+o[0], r+o[1] {
+				rays[dir][sq] |= makeSquare(f, r).bitboard()
+			}
+		}
+	}
+}
+
+// slidingAttacks returns the squares a slider on sq attacks along the
+// directions; each ray stops at the first occupied square.
+func slidingAttacks(sq Square, occupied Bitboard, dirs [4]int) Bitboard {
+	var attacks Bitboard
+	for _, dir := range dirs {
+		ray := rays[dir][sq]
+		if blockers := ray & occupied; blockers != 0 {
+			if dir < dirS {
+				ray &^= rays[dir][blockers.first()]
+			} else {
+				ray &^= rays[dir][blockers.last()]
+			}
+		}
+		attacks |= ray
+	}
+	return attacks
+}
+
+func rookAttacks(sq Square, occupied Bitboard) Bitboard {
+	return slidingAttacks(sq, occupied, [4]int{dirN, dirE, dirS, dirW})
+}
+
+func bishopAttacks(sq Square, occupied Bitboard) Bitboard {
+	return slidingAttacks(sq, occupied, [4]int{dirNE, dirNW, dirSW, dirSE})
+}
@@ -2,253 +2,367 @@ package uci
 
 import (
 	"fmt"
-	"math"
+	"strconv"
 	"strings"
 	"unicode"
 )
 
 type Board struct {
-	Pos             []rune
-	ActiveColor     string
-	Castling        string
-	EnPassantSquare string
-	HalfmoveClock   string
-	FullMove        string
+	ActiveColor     Color
+	EnPassantSquare Square
+	HalfmoveClock   int
+	FullMove        int
+
+	// Chess960 writes castling as the king taking its own rook (e1h1) instead
+	// of moving two squares (e1g1).
+	Chess960 bool
+
+	pieces   [13]Bitboard // indexed by Piece, pieces[NoPiece] is unused
+	colors   [2]Bitboard
+	squares  [64]Piece
+	castling castlingRooks
+
+	key uint64
+}
+
+// PieceAt returns the piece on the square, or NoPiece.
+func (b *Board) PieceAt(sq Square) Piece {
+	return b.squares[sq]
+}
+
+// Pieces returns the squares holding the piece.
+func (b *Board) Pieces(p Piece) Bitboard {
+	return b.pieces[p]
+}
+
+// Occupied returns the squares holding a piece of the color.
+func (b *Board) Occupied(c Color) Bitboard {
+	return b.colors[c]
+}
+
+// Castling returns the castling rights as written in a FEN.
+func (b *Board) Castling() string {
+	return b.formatCastling(b.castling)
 }
 
 func (b *Board) FEN() string {
 	var fen strings.Builder
-	for i := 0; i < 8; i++ {
-		if fen.Len() != 0 {
+	for rank := 7; rank >= 0; rank-- {
+		if rank != 7 {
 			fen.WriteRune('/')
 		}
 
-		offset := i * 8
 		blanks := 0
 
-		for j := 0; j < 8; j++ {
-			if b.Pos[offset+j] == ' ' {
+		for file := 0; file < 8; file++ {
+			p := b.squares[makeSquare(file, rank)]
+			if p == NoPiece {
 				blanks++
 				continue
 			}
 
 			if blanks != 0 {
-				fen.WriteString(fmt.Sprintf("%d", blanks))
+				fen.WriteString(strconv.Itoa(blanks))
 				blanks = 0
 			}
 
-			fen.WriteRune(b.Pos[offset+j])
+			fen.WriteRune(p.rune())
 		}
 
 		if blanks != 0 {
-			fen.WriteString(fmt.Sprintf("%d", blanks))
-			blanks = 0
+			fen.WriteString(strconv.Itoa(blanks))
 		}
 	}
 
-	fen.WriteString(fmt.Sprintf(" %s %s %s %s %s", b.ActiveColor, b.Castling, b.EnPassantSquare, b.HalfmoveClock, b.FullMove))
+	fen.WriteString(fmt.Sprintf(" %s %s %s %d %d", b.ActiveColor, b.Castling(), b.EnPassantSquare, b.HalfmoveClock, b.FullMove))
 
 	return fen.String()
 }
 
+// Moves plays the moves, given in UCI notation. The moves aren't checked for
+// legality; see IsLegalMove. It panics if a move is malformed.
 func (b *Board) Moves(moves ...string) {
-	if len(moves) == 0 {
-		return
+	for _, move := range moves {
+		m, err := ParseMove(move)
+		if err != nil {
+			panic(err)
+		}
+		b.MakeMove(m)
 	}
+}
 
-	halfMoveClock := atoi(b.HalfmoveClock)
-	fullMove := atoi(b.FullMove)
+// MakeMove plays the move. The move isn't checked for legality.
+func (b *Board) MakeMove(m Move) {
+	from, to := m.From(), m.To()
+	piece := b.squares[from]
+	us := b.ActiveColor
 
-	var activeColor int
-	if b.ActiveColor == "b" {
-		activeColor = 1
+	b.key ^= b.castling.key() ^ b.enPassantKey() ^ polyglotRandom[polyglotTurn]
+
+	castle := b.castlingSide(from, to, b.castling)
+	var rookFrom Square
+	if castle != -1 {
+		rookFrom = makeSquare(b.castling[castle], castlingRank(castle))
 	}
 
-	var wk, wq, bk, bq bool
-	for _, c := range b.Castling {
-		switch c {
-		case 'K':
-			wk = true
-		case 'Q':
-			wq = true
-		case 'k':
-			bk = true
-		case 'q':
-			bq = true
+	// castling privileges; a move can touch two corners (Qa1xh8)
+	if piece.Type() == King {
+		right := castleWhiteKing
+		if us == Black {
+			right = castleBlackKing
 		}
+		b.castling[right], b.castling[right+1] = -1, -1
 	}
-
-	for _, move := range moves {
-		if activeColor == 1 {
-			activeColor = 0
-			fullMove++
-		} else {
-			activeColor = 1
+	for right, file := range b.castling {
+		if file == -1 {
+			continue
 		}
-
-		fromUCI := move[:2]
-		toUCI := move[2:4]
-		var promote string
-		if len(move) > 4 {
-			promote = string(move[4])
+		if sq := makeSquare(file, castlingRank(right)); sq == from || sq == to {
+			b.castling[right] = -1
 		}
+	}
 
-		// castling privileges
-		if fromUCI == "a1" || toUCI == "a1" {
-			wq = false
-		} else if fromUCI == "h1" || toUCI == "h1" {
-			wk = false
-		} else if fromUCI == "a8" || toUCI == "a8" {
-			bq = false
-		} else if fromUCI == "h8" || toUCI == "h8" {
-			bk = false
-		} else if fromUCI == "e1" {
-			wk, wq = false, false
-		} else if fromUCI == "e8" {
-			bk, bq = false, false
-		}
+	ep := b.EnPassantSquare
+	b.EnPassantSquare = NoSquare
+	b.HalfmoveClock++
 
-		from, to := uciToIndex(fromUCI), uciToIndex(toUCI)
-		piece := b.Pos[from]
-		isCapture := b.Pos[to] != ' '
-		b.Pos[to] = b.Pos[from]
-		b.Pos[from] = ' '
-
-		if toUCI == b.EnPassantSquare && (piece == 'P' || piece == 'p') {
-			var captureOn int
-			if activeColor == 0 {
-				captureOn = to - 8 // next move is white's, so the target is in black's position
-			} else {
-				captureOn = to + 8
-			}
-			b.Pos[captureOn] = ' '
-			isCapture = true
+	if castle != -1 {
+		// the king and rook may swap squares or stay put in Chess960, so
+		// clear both before placing them
+		rank := castlingRank(castle)
+		kingFile, rookFile := castlingTargets(castle)
+		rook := b.squares[rookFrom]
+		b.remove(from)
+		b.remove(rookFrom)
+		b.put(piece, makeSquare(kingFile, rank))
+		b.put(rook, makeSquare(rookFile, rank))
+	} else {
+		if b.squares[to] != NoPiece {
+			b.remove(to)
+			b.HalfmoveClock = 0
 		}
+		b.remove(from)
 
-		// pawn move, reset halfmove clock; en passant square
-		b.EnPassantSquare = "-"
-		if piece == 'P' || piece == 'p' {
-			halfMoveClock = 0
-			if int(math.Abs(float64(to-from))) == 16 {
-				var file rune
-				if activeColor == 0 {
-					file = '6' // next move is white's, so the target is in black's position
-				} else {
-					file = '3'
-				}
-				b.EnPassantSquare = fmt.Sprintf("%c%c", 'a'+to%8, file)
+		// pawn move, reset halfmove clock; en passant square; promotion
+		if piece.Type() == Pawn {
+			b.HalfmoveClock = 0
+			if to == ep {
+				// the captured pawn is next to the pawn which took it
+				b.remove(makeSquare(to.File(), from.Rank()))
 			}
-		} else {
-			if isCapture {
-				halfMoveClock = 0
-			} else {
-				halfMoveClock++
+			if to-from == 16 || from-to == 16 {
+				b.EnPassantSquare = (from + to) / 2
 			}
-		}
-
-		// promotion
-		if promote != "" {
-			if activeColor == 0 {
-				b.Pos[to] = rune(promote[0])
-			} else {
-				b.Pos[to] = rune(promote[0] - 32)
+			if p := m.Promotion(); p != NoPieceType {
+				piece = makePiece(us, p)
 			}
 		}
 
-		// white king castle
-		if piece == 'K' && fromUCI == "e1" {
-			if toUCI == "g1" {
-				// king side
-				b.Pos[to+1] = ' '
-				b.Pos[to-1] = 'R'
-			} else if toUCI == "c1" {
-				// queen side
-				b.Pos[to-2] = ' '
-				b.Pos[to+1] = 'R'
-			}
-		}
+		b.put(piece, to)
+	}
 
-		// black king castle
-		if piece == 'k' && fromUCI == "e8" {
-			if toUCI == "g8" {
-				// king side
-				b.Pos[to+1] = ' '
-				b.Pos[to-1] = 'r'
-			} else if toUCI == "c8" {
-				// queen side
-				b.Pos[to-2] = ' '
-				b.Pos[to+1] = 'r'
-			}
-		}
+	if us == Black {
+		b.FullMove++
 	}
+	b.ActiveColor = us.Other()
 
-	if activeColor == 0 {
-		b.ActiveColor = "w"
-	} else {
-		b.ActiveColor = "b"
+	b.key ^= b.castling.key() ^ b.enPassantKey()
+}
+
+// put places the piece on an empty square and updates the position key.
+func (b *Board) put(p Piece, sq Square) {
+	if p == NoPiece {
+		return
 	}
+	bb := sq.bitboard()
+	b.pieces[p] |= bb
+	b.colors[p.Color()] |= bb
+	b.squares[sq] = p
+	b.key ^= pieceKeys[p][sq]
+}
 
-	// castling
-	var cstl strings.Builder
-	if wk {
-		cstl.WriteRune('K')
+// remove clears the square and updates the position key.
+func (b *Board) remove(sq Square) {
+	p := b.squares[sq]
+	if p == NoPiece {
+		return
 	}
-	if wq {
-		cstl.WriteRune('Q')
+	bb := sq.bitboard()
+	b.pieces[p] &^= bb
+	b.colors[p.Color()] &^= bb
+	b.squares[sq] = NoPiece
+	b.key ^= pieceKeys[p][sq]
+}
+
+func (b *Board) occupied() Bitboard {
+	return b.colors[White] | b.colors[Black]
+}
+
+// empty returns true for the zero Board, before a position is set.
+func (b *Board) empty() bool {
+	return b.occupied() == 0
+}
+
+// FENtoBoard parses a FEN which is known to be valid. It panics if the FEN is
+// malformed; use ParseFEN for input from a GUI or a file.
+func FENtoBoard(fen string) Board {
+	b, err := ParseFEN(fen)
+	if err != nil {
+		panic(err)
 	}
-	if bk {
-		cstl.WriteRune('k')
+	return b
+}
+
+// ParseFEN parses and validates a FEN. The halfmove clock and fullmove number
+// may be omitted, in which case they default to "0 1".
+func ParseFEN(fen string) (Board, error) {
+	b, err := parseFEN(fen)
+	if err != nil {
+		return Board{}, fmt.Errorf("invalid FEN '%s': %v", fen, err)
 	}
-	if bq {
-		cstl.WriteRune('q')
+	return b, nil
+}
+
+func parseFEN(fen string) (Board, error) {
+	parts := strings.Fields(fen)
+	if len(parts) == 4 {
+		parts = append(parts, "0", "1")
 	}
-	if cstl.Len() == 0 {
-		b.Castling = "-"
-	} else {
-		b.Castling = cstl.String()
+	if len(parts) != 6 {
+		return Board{}, fmt.Errorf("want 6 fields, got %d", len(parts))
 	}
 
-	// NOTE: en passant target square handling per move
+	ranks := strings.Split(parts[0], "/")
+	if len(ranks) != 8 {
+		return Board{}, fmt.Errorf("want 8 ranks, got %d", len(ranks))
+	}
 
-	b.HalfmoveClock = fmt.Sprintf("%d", halfMoveClock)
-	b.FullMove = fmt.Sprintf("%d", fullMove)
-}
+	var b Board
 
-func FENtoBoard(fen string) Board {
-	parts := strings.Split(fen, " ")
-	ranks := strings.Split(parts[0], "/")
-	b := Board{
-		ActiveColor:     parts[1],
-		Castling:        parts[2],
-		EnPassantSquare: parts[3],
-		HalfmoveClock:   parts[4],
-		FullMove:        parts[5],
-		Pos:             make([]rune, 64),
-	}
-
-	for i := 7; i >= 0; i-- {
-		rank := ranks[i]
-		offset := i * 8
+	for i, rank := range ranks {
+		file := 0
+		prevDigit := false
 		for _, c := range rank {
 			if unicode.IsDigit(c) {
 				n := int(c) - 48
-				for j := 0; j < n; j++ {
-					b.Pos[offset] = ' '
-					offset++
+				if n < 1 || n > 8 || prevDigit {
+					return Board{}, fmt.Errorf("rank %d '%s' is malformed", 8-i, rank)
 				}
-			} else {
-				b.Pos[offset] = c
-				offset++
+				prevDigit = true
+				file += n
+				continue
 			}
+
+			prevDigit = false
+			p := pieceFromRune(c)
+			if p == NoPiece {
+				return Board{}, fmt.Errorf("rank %d has unknown piece '%c'", 8-i, c)
+			}
+			if file < 8 {
+				b.put(p, makeSquare(file, 7-i))
+			}
+			file++
+		}
+
+		if n := squareCount(rank); n != 8 {
+			return Board{}, fmt.Errorf("rank %d '%s' has %d squares", 8-i, rank, n)
 		}
 	}
 
-	return b
+	switch parts[1] {
+	case "w":
+		b.ActiveColor = White
+	case "b":
+		b.ActiveColor = Black
+	default:
+		return Board{}, fmt.Errorf("side to move '%s' is not 'w' or 'b'", parts[1])
+	}
+
+	var err error
+	if b.castling, err = b.parseCastling(parts[2]); err != nil {
+		return Board{}, err
+	}
+
+	if b.EnPassantSquare, err = b.parseEnPassant(parts[3]); err != nil {
+		return Board{}, err
+	}
+
+	if b.HalfmoveClock, err = strconv.Atoi(parts[4]); err != nil || b.HalfmoveClock < 0 {
+		return Board{}, fmt.Errorf("halfmove clock '%s' is not a non-negative number", parts[4])
+	}
+
+	if b.FullMove, err = strconv.Atoi(parts[5]); err != nil || b.FullMove < 1 {
+		return Board{}, fmt.Errorf("fullmove number '%s' is not a positive number", parts[5])
+	}
+
+	if err := b.validatePieces(); err != nil {
+		return Board{}, err
+	}
+
+	b.key = b.computeKey()
+
+	return b, nil
+}
+
+func squareCount(rank string) int {
+	var n int
+	for _, c := range rank {
+		if unicode.IsDigit(c) {
+			n += int(c) - 48
+		} else {
+			n++
+		}
+	}
+	return n
+}
+
+func (b *Board) parseEnPassant(ep string) (Square, error) {
+	if ep == "-" {
+		return NoSquare, nil
+	}
+
+	sq, err := ParseSquare(ep)
+	if err != nil {
+		return NoSquare, fmt.Errorf("en passant square '%s' is malformed", ep)
+	}
+
+	// the pawn which just moved two squares is in front of the target square
+	// and the square it came from is empty
+	wantRank, pawn, dir := 5, BlackPawn, Square(8)
+	if b.ActiveColor == Black {
+		wantRank, pawn, dir = 2, WhitePawn, -8
+	}
+
+	if sq.Rank() != wantRank {
+		return NoSquare, fmt.Errorf("en passant square '%s' must be on rank %d with %s to move", ep, wantRank+1, b.ActiveColor)
+	}
+
+	if b.squares[sq] != NoPiece || b.squares[sq+dir] != NoPiece || b.squares[sq-dir] != pawn {
+		return NoSquare, fmt.Errorf("en passant square '%s' doesn't follow a double pawn push", ep)
+	}
+
+	return sq, nil
+}
+
+func (b *Board) validatePieces() error {
+	if pawns := (b.pieces[WhitePawn] | b.pieces[BlackPawn]) & (rankMask(0) | rankMask(7)); pawns != 0 {
+		return fmt.Errorf("pawn on %s", pawns.first())
+	}
+
+	whiteKings, blackKings := b.pieces[WhiteKing].Count(), b.pieces[BlackKing].Count()
+	if whiteKings != 1 || blackKings != 1 {
+		return fmt.Errorf("want one king per side, got %d white and %d black", whiteKings, blackKings)
+	}
+
+	// the side which just moved can't have left its king in check
+	if b.isAttacked(b.kingSquare(b.ActiveColor.Other()), b.ActiveColor) {
+		return fmt.Errorf("side not to move is in check")
+	}
+
+	return nil
 }
 
-func uciToIndex(uci string) int {
-	file := int(uci[0]) - 'a'
-	rank := int(uci[1]) - '0' - 1
-	idx := (7-rank)*8 + file
-	return idx
+// rankMask returns the squares on the rank (0-7).
+func rankMask(rank int) Bitboard {
+	return Bitboard(0xff) << uint(8*rank)
 }
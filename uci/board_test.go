@@ -11,20 +11,20 @@ func TestFENtoBoard(t *testing.T) {
 	// arrange
 	cases := []struct {
 		fen                 string
-		wantActiveColor     string
+		wantActiveColor     Color
 		wantCastling        string
-		wantEnPassantSquare string
-		wantHalfMoveClock   string
-		wantFullMove        string
+		wantEnPassantSquare Square
+		wantHalfMoveClock   int
+		wantFullMove        int
 		wantPos             []rune
 	}{
 		{
 			fen:                 "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1",
-			wantActiveColor:     "w",
+			wantActiveColor:     White,
 			wantCastling:        "KQkq",
-			wantEnPassantSquare: "-",
-			wantHalfMoveClock:   "0",
-			wantFullMove:        "1",
+			wantEnPassantSquare: NoSquare,
+			wantHalfMoveClock:   0,
+			wantFullMove:        1,
 			wantPos: []rune{
 				'r', 'n', 'b', 'q', 'k', 'b', 'n', 'r',
 				'p', 'p', 'p', 'p', 'p', 'p', 'p', 'p',
@@ -38,11 +38,11 @@ func TestFENtoBoard(t *testing.T) {
 		},
 		{
 			fen:                 "r1b1kbnr/pppp1ppp/2n5/4P3/1q6/5N2/PPPBPPPP/RN1QKB1R b KQkq - 6 5",
-			wantActiveColor:     "b",
+			wantActiveColor:     Black,
 			wantCastling:        "KQkq",
-			wantEnPassantSquare: "-",
-			wantHalfMoveClock:   "6",
-			wantFullMove:        "5",
+			wantEnPassantSquare: NoSquare,
+			wantHalfMoveClock:   6,
+			wantFullMove:        5,
 			wantPos: []rune{
 				'r', ' ', 'b', ' ', 'k', 'b', 'n', 'r',
 				'p', 'p', 'p', 'p', ' ', 'p', 'p', 'p',
@@ -69,7 +69,11 @@ func TestFENtoBoard(t *testing.T) {
 			board := FENtoBoard(c.fen)
 
 			// assert
-			if !reflect.DeepEqual(c.wantPos, board.Pos) {
+			pos := make([]rune, 64)
+			for i := range pos {
+				pos[i] = board.PieceAt(makeSquare(i%8, 7-i/8)).rune()
+			}
+			if !reflect.DeepEqual(c.wantPos, pos) {
 				var (
 					loc       string
 					want, got strings.Builder
@@ -87,7 +91,7 @@ func TestFENtoBoard(t *testing.T) {
 
 					for j := 0; j < 8; j++ {
 						idx := offset + j
-						if c.wantPos[idx] != board.Pos[idx] {
+						if c.wantPos[idx] != pos[idx] {
 							file := 'a' + j
 							if loc != "" {
 								loc += ", "
@@ -96,7 +100,7 @@ func TestFENtoBoard(t *testing.T) {
 						}
 
 						want.WriteString(sq(c.wantPos[idx]))
-						got.WriteString(sq(board.Pos[idx]))
+						got.WriteString(sq(pos[idx]))
 					}
 
 					writeBoth("\n")
@@ -109,17 +113,17 @@ func TestFENtoBoard(t *testing.T) {
 			if board.ActiveColor != c.wantActiveColor {
 				t.Errorf("ActiveColor, want: '%s' got: '%s'", c.wantActiveColor, board.ActiveColor)
 			}
-			if board.Castling != c.wantCastling {
-				t.Errorf("Castling, want: '%s' got: '%s'", c.wantCastling, board.Castling)
+			if board.Castling() != c.wantCastling {
+				t.Errorf("Castling, want: '%s' got: '%s'", c.wantCastling, board.Castling())
 			}
 			if board.EnPassantSquare != c.wantEnPassantSquare {
 				t.Errorf("EnPassantSquare, want: '%s' got: '%s'", c.wantEnPassantSquare, board.EnPassantSquare)
 			}
 			if board.HalfmoveClock != c.wantHalfMoveClock {
-				t.Errorf("HalfmoveClock, want: '%s' got: '%s'", c.wantHalfMoveClock, board.HalfmoveClock)
+				t.Errorf("HalfmoveClock, want: %d got: %d", c.wantHalfMoveClock, board.HalfmoveClock)
 			}
 			if board.FullMove != c.wantFullMove {
-				t.Errorf("FullMove, want: '%s' got: '%s'", c.wantFullMove, board.FullMove)
+				t.Errorf("FullMove, want: %d got: %d", c.wantFullMove, board.FullMove)
 			}
 		})
 	}
@@ -218,3 +222,85 @@ func TestMakeMoves(t *testing.T) {
 		})
 	}
 }
+
+func TestParseFEN(t *testing.T) {
+	// arrange
+	cases := []struct {
+		fen     string
+		wantErr string
+		wantFEN string
+	}{
+		{fen: startPosFEN, wantFEN: startPosFEN},
+		{fen: "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq -", wantFEN: startPosFEN},
+		{fen: "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1", wantFEN: "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1"},
+		{fen: "4k3/8/8/8/8/8/8/4K3 w - - 12 40", wantFEN: "4k3/8/8/8/8/8/8/4K3 w - - 12 40"},
+		{fen: "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w HAha - 0 1", wantFEN: startPosFEN},
+		{fen: "bqnb1rkr/pp3ppp/3ppn2/2p5/5P2/P2P4/NPP1P1PP/BQ1BNRKR w HFhf - 2 9", wantFEN: "bqnb1rkr/pp3ppp/3ppn2/2p5/5P2/P2P4/NPP1P1PP/BQ1BNRKR w KQkq - 2 9"},
+		{fen: "", wantErr: "want 6 fields, got 0"},
+		{fen: "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0", wantErr: "want 6 fields, got 5"},
+		{fen: "rnbqkbnr/pppppppp/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", wantErr: "want 8 ranks, got 7"},
+		{fen: "rnbqkbnr/pppppppp/8/8/8/9/PPPPPPPP/RNBQKBNR w KQkq - 0 1", wantErr: "rank 3 '9' is malformed"},
+		{fen: "rnbqkbnr/pppppppp/8/8/44/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", wantErr: "rank 4 '44' is malformed"},
+		{fen: "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPPP/RNBQKBNR w KQkq - 0 1", wantErr: "rank 2 'PPPPPPPPP' has 9 squares"},
+		{fen: "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPP/RNBQKBNR w KQkq - 0 1", wantErr: "rank 2 'PPPPPPP' has 7 squares"},
+		{fen: "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNX w KQkq - 0 1", wantErr: "rank 1 has unknown piece 'X'"},
+		{fen: "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR x KQkq - 0 1", wantErr: "side to move 'x' is not 'w' or 'b'"},
+		{fen: "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkqk - 0 1", wantErr: "castling 'KQkqk' repeats 'k'"},
+		{fen: "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkx - 0 1", wantErr: "castling 'KQkx' has unknown right 'x'"},
+		{fen: "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBN1 w KQkq - 0 1", wantErr: "castling right 'K' has no R to castle with"},
+		{fen: "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQ1BNR w KQkq - 0 1", wantErr: "castling right 'K' needs K on rank 1"},
+		{fen: "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkqc - 0 1", wantErr: "castling right 'c' has no r to castle with"},
+		{fen: "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkqa - 0 1", wantErr: "castling 'KQkqa' repeats 'a'"},
+		{fen: "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e6 0 1", wantErr: "en passant square 'e6' must be on rank 3 with b to move"},
+		{fen: "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR b KQkq e3 0 1", wantErr: "en passant square 'e3' doesn't follow a double pawn push"},
+		{fen: "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq z3 0 1", wantErr: "en passant square 'z3' is malformed"},
+		{fen: "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - -1 1", wantErr: "halfmove clock '-1' is not a non-negative number"},
+		{fen: "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 0", wantErr: "fullmove number '0' is not a positive number"},
+		{fen: "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 x", wantErr: "fullmove number 'x' is not a positive number"},
+		{fen: "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQQBNR w kq - 0 1", wantErr: "want one king per side, got 0 white and 1 black"},
+		{fen: "4k3/8/8/8/8/8/8/4KK2 w - - 0 1", wantErr: "want one king per side, got 2 white and 1 black"},
+		{fen: "4k3/8/8/8/8/8/8/P3K3 w - - 0 1", wantErr: "pawn on a1"},
+		{fen: "4k2R/8/8/8/8/8/8/4K3 w - - 0 1", wantErr: "side not to move is in check"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.fen, func(t *testing.T) {
+			// act
+			b, err := ParseFEN(c.fen)
+
+			// assert
+			if c.wantErr != "" {
+				want := fmt.Sprintf("invalid FEN '%s': %s", c.fen, c.wantErr)
+				if err == nil || err.Error() != want {
+					t.Errorf("\nwant err: %s\ngot err:  %v", want, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := b.FEN(); got != c.wantFEN {
+				t.Errorf("\nwant: '%s'\ngot:  '%s'", c.wantFEN, got)
+			}
+		})
+	}
+}
+
+func BenchmarkFENtoBoard(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		FENtoBoard("r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1")
+	}
+}
+
+// BenchmarkMovesPV replays a principal variation the way the 'info' handler
+// does for every line Stockfish sends.
+func BenchmarkMovesPV(b *testing.B) {
+	board := FENtoBoard("r1bqkbnr/pppp1ppp/2n5/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R w KQkq - 2 3")
+	pv := strings.Split("f1b5 a7a6 b5a4 g8f6 e1g1 f8e7 f1e1 b7b5 a4b3 d7d6 c2c3 e8g8 h2h3 c6a5 b3c2 c7c5 d2d4 d8c7 b1d2 c5d4", " ")
+	for i := 0; i < b.N; i++ {
+		c := board.clone()
+		c.Moves(pv...)
+		c.FEN()
+	}
+}
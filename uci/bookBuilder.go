@@ -0,0 +1,244 @@
+package uci
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// BookFilter selects the games and moves a BookBuilder learns from.
+type BookFilter struct {
+	// MinRating is the lowest WhiteElo and BlackElo a game can have; games
+	// without ratings are skipped unless it's 0.
+	MinRating int
+
+	// Results are the results of the games to use, e.g. "1-0"; empty means
+	// every finished game.
+	Results []string
+
+	// MaxPly is how many plies of each game are used, 0 for all of them.
+	MaxPly int
+
+	// MinGames is how many games a move must be played in to make the book.
+	MinGames int
+}
+
+// BookBuilder collects move statistics from games to build an opening book.
+// Only finished games are counted, "1-0", "0-1" or "1/2-1/2". Positions are
+// keyed by their Polyglot key, so lines which transpose share their moves.
+type BookBuilder struct {
+	Filter BookFilter
+
+	// Games and Skipped count the games added and the games filtered out.
+	Games   int
+	Skipped int
+
+	positions map[uint64]map[Move]*MoveStats
+}
+
+// MoveStats are the results of the games a move was played in, from the
+// point of view of the side which played it.
+type MoveStats struct {
+	Move   string
+	Wins   int
+	Draws  int
+	Losses int
+
+	// polyglot is the move in Polyglot's encoding, which needs the position
+	// to tell castling apart
+	polyglot uint16
+}
+
+// Games returns how many games the move was played in.
+func (s MoveStats) Games() int {
+	return s.Wins + s.Draws + s.Losses
+}
+
+// Score returns the percentage of points the move scored, a draw being half a
+// point.
+func (s MoveStats) Score() float64 {
+	if s.Games() == 0 {
+		return 0
+	}
+	return float64(2*s.Wins+s.Draws) * 50 / float64(s.Games())
+}
+
+// Weight is the book weight of the move: two for a win and one for a draw,
+// its score in half points. A move which only lost is never played, and a
+// move which scores the same in more games weighs more.
+func (s MoveStats) Weight() int {
+	return 2*s.Wins + s.Draws
+}
+
+// NewBookBuilder returns a builder using the filter.
+func NewBookBuilder(filter BookFilter) *BookBuilder {
+	return &BookBuilder{
+		Filter:    filter,
+		positions: make(map[uint64]map[Move]*MoveStats),
+	}
+}
+
+// AddGame adds the game's moves, up to the filter's MaxPly, and returns false
+// if the filter skipped the game.
+func (bb *BookBuilder) AddGame(g *Game) bool {
+	if !bb.accept(g) {
+		bb.Skipped++
+		return false
+	}
+	bb.Games++
+
+	ply := 0
+	g.Replay(func(b *Board, gm GameMove) {
+		if bb.Filter.MaxPly != 0 && ply >= bb.Filter.MaxPly {
+			return
+		}
+		ply++
+
+		m, err := ParseMove(gm.Move)
+		if err != nil {
+			return
+		}
+
+		key := b.Key()
+		moves := bb.positions[key]
+		if moves == nil {
+			moves = make(map[Move]*MoveStats)
+			bb.positions[key] = moves
+		}
+		s := moves[m]
+		if s == nil {
+			s = &MoveStats{Move: gm.Move, polyglot: b.encodePolyglotMove(m)}
+			moves[m] = s
+		}
+
+		switch {
+		case g.Result == "1/2-1/2":
+			s.Draws++
+		case (g.Result == "1-0") == (b.ActiveColor == White):
+			s.Wins++
+		default:
+			s.Losses++
+		}
+	})
+
+	return true
+}
+
+func (bb *BookBuilder) accept(g *Game) bool {
+	switch g.Result {
+	case "1-0", "0-1", "1/2-1/2":
+	default:
+		return false
+	}
+	if len(bb.Filter.Results) != 0 && !containsString(bb.Filter.Results, g.Result) {
+		return false
+	}
+
+	if bb.Filter.MinRating != 0 {
+		white, err1 := strconv.Atoi(g.Tag("WhiteElo"))
+		black, err2 := strconv.Atoi(g.Tag("BlackElo"))
+		if err1 != nil || err2 != nil || white < bb.Filter.MinRating || black < bb.Filter.MinRating {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Stats returns the moves played in the position which pass the filter's
+// MinGames, most played first.
+func (bb *BookBuilder) Stats(b *Board) []MoveStats {
+	var stats []MoveStats
+	for _, s := range bb.positions[b.Key()] {
+		if s.Games() >= max(bb.Filter.MinGames, 1) {
+			stats = append(stats, *s)
+		}
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Games() != stats[j].Games() {
+			return stats[i].Games() > stats[j].Games()
+		}
+		return stats[i].Move < stats[j].Move
+	})
+	return stats
+}
+
+// Polyglot returns the book in the Polyglot format. Where a position's
+// heaviest move is over 65535, all of its weights are scaled down together to
+// fit Polyglot's 16 bits, keeping their ratios.
+func (bb *BookBuilder) Polyglot() *PolyglotBook {
+	var book PolyglotBook
+	for key, moves := range bb.positions {
+		var stats []*MoveStats
+		maxWeight := 0
+		for _, s := range moves {
+			if s.Games() >= max(bb.Filter.MinGames, 1) {
+				stats = append(stats, s)
+				maxWeight = max(maxWeight, s.Weight())
+			}
+		}
+
+		for _, s := range stats {
+			weight := s.Weight()
+			if maxWeight > 0xffff {
+				weight = weight * 0xffff / maxWeight
+			}
+			book.Entries = append(book.Entries, PolyglotEntry{Key: key, Move: s.polyglot, Weight: uint16(weight)})
+		}
+	}
+
+	sort.Slice(book.Entries, func(i, j int) bool {
+		a, b := book.Entries[i], book.Entries[j]
+		if a.Key != b.Key {
+			return a.Key < b.Key
+		}
+		if a.Weight != b.Weight {
+			return a.Weight > b.Weight
+		}
+		return a.Move < b.Move
+	})
+
+	return &book
+}
+
+// WriteRepertoire writes the book as a repertoire file: a weighted 'play' line
+// for every line of the book from the starting position, see repertoire.txt.
+// A position reached by transposition is only followed the first time.
+func (bb *BookBuilder) WriteRepertoire(w io.Writer) error {
+	var out strings.Builder
+	out.WriteString(fmt.Sprintf("# built from %d games, %d skipped\n", bb.Games, bb.Skipped))
+
+	visited := make(map[uint64]bool)
+
+	var walk func(b Board, line []string, ply int)
+	walk = func(b Board, line []string, ply int) {
+		var stats []MoveStats
+		if !visited[b.Key()] && (bb.Filter.MaxPly == 0 || ply < bb.Filter.MaxPly) {
+			stats = bb.Stats(&b)
+		}
+		visited[b.Key()] = true
+
+		if len(stats) == 0 {
+			if len(line) != 0 {
+				out.WriteString("play " + strings.Join(line, " ") + "\n")
+			}
+			return
+		}
+
+		for _, s := range stats {
+			san := b.sanOrUCI(s.Move)
+			if b.ActiveColor == White {
+				san = fmt.Sprintf("%d. %s", b.FullMove, san)
+			}
+			c := b.clone()
+			c.Moves(s.Move)
+			walk(c, append(line[:len(line):len(line)], fmt.Sprintf("%s:%d", san, s.Weight())), ply+1)
+		}
+	}
+	walk(FENtoBoard(startPosFEN), nil, 0)
+
+	_, err := io.WriteString(w, out.String())
+	return err
+}
@@ -0,0 +1,246 @@
+package uci
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const bookBuilderPGN = `[White "a"]
+[Black "b"]
+[WhiteElo "2600"]
+[BlackElo "2550"]
+[Result "1-0"]
+
+1. e4 e5 2. Nf3 Nc6 3. Bc4 Nf6 4. O-O 1-0
+
+[White "c"]
+[Black "d"]
+[WhiteElo "2500"]
+[BlackElo "2700"]
+[Result "1/2-1/2"]
+
+1. e4 c5 2. Nf3 d6 1/2-1/2
+
+[White "e"]
+[Black "f"]
+[WhiteElo "2650"]
+[BlackElo "2650"]
+[Result "0-1"]
+
+1. d4 Nf6 2. c4 e6 0-1
+
+[White "g"]
+[Black "h"]
+[WhiteElo "1800"]
+[BlackElo "2650"]
+[Result "0-1"]
+
+1. d4 d5 0-1
+
+[White "i"]
+[Black "j"]
+[Result "*"]
+
+1. e4 e5 *
+`
+
+func newTestBookBuilder(t *testing.T, filter BookFilter) *BookBuilder {
+	t.Helper()
+
+	games, err := ReadPGN(strings.NewReader(bookBuilderPGN))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bb := NewBookBuilder(filter)
+	for _, g := range games {
+		bb.AddGame(g)
+	}
+	return bb
+}
+
+func TestBookBuilderStats(t *testing.T) {
+	// arrange
+	cases := []struct {
+		name        string
+		filter      BookFilter
+		moves       string
+		want        []MoveStats
+		wantGames   int
+		wantSkipped int
+	}{
+		{
+			name:        "first moves",
+			filter:      BookFilter{},
+			want:        []MoveStats{{Move: "d2d4", Losses: 2}, {Move: "e2e4", Wins: 1, Draws: 1}},
+			wantGames:   4,
+			wantSkipped: 1,
+		},
+		{
+			name:        "min rating",
+			filter:      BookFilter{MinRating: 2500},
+			want:        []MoveStats{{Move: "e2e4", Wins: 1, Draws: 1}, {Move: "d2d4", Losses: 1}},
+			wantGames:   3,
+			wantSkipped: 2,
+		},
+		{
+			name:        "results",
+			filter:      BookFilter{Results: []string{"1-0", "1/2-1/2"}},
+			moves:       "e2e4",
+			want:        []MoveStats{{Move: "c7c5", Draws: 1}, {Move: "e7e5", Losses: 1}},
+			wantGames:   2,
+			wantSkipped: 3,
+		},
+		{
+			name:        "black wins",
+			filter:      BookFilter{},
+			moves:       "d2d4",
+			want:        []MoveStats{{Move: "d7d5", Wins: 1}, {Move: "g8f6", Wins: 1}},
+			wantGames:   4,
+			wantSkipped: 1,
+		},
+		{
+			name:        "min games",
+			filter:      BookFilter{MinGames: 2},
+			moves:       "d2d4",
+			want:        nil,
+			wantGames:   4,
+			wantSkipped: 1,
+		},
+		{
+			name:        "max ply",
+			filter:      BookFilter{MaxPly: 2},
+			moves:       "e2e4 e7e5",
+			want:        nil,
+			wantGames:   4,
+			wantSkipped: 1,
+		},
+		{
+			name:        "castling",
+			filter:      BookFilter{},
+			moves:       "e2e4 e7e5 g1f3 b8c6 f1c4 g8f6",
+			want:        []MoveStats{{Move: "e1g1", Wins: 1}},
+			wantGames:   4,
+			wantSkipped: 1,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			bb := newTestBookBuilder(t, c.filter)
+			b := FENtoBoard(startPosFEN)
+			b.Moves(strings.Fields(c.moves)...)
+
+			// act
+			got := bb.Stats(&b)
+
+			// assert
+			for i := range got {
+				got[i].polyglot = 0
+			}
+			if !reflect.DeepEqual(got, c.want) {
+				t.Errorf("want: %v got: %v", c.want, got)
+			}
+			if bb.Games != c.wantGames || bb.Skipped != c.wantSkipped {
+				t.Errorf("games/skipped want: %d/%d got: %d/%d", c.wantGames, c.wantSkipped, bb.Games, bb.Skipped)
+			}
+		})
+	}
+}
+
+func TestMoveStatsScore(t *testing.T) {
+	// arrange
+	cases := []struct {
+		stats      MoveStats
+		wantScore  float64
+		wantWeight int
+	}{
+		{stats: MoveStats{}, wantScore: 0, wantWeight: 0},
+		{stats: MoveStats{Wins: 1, Draws: 1}, wantScore: 75, wantWeight: 3},
+		{stats: MoveStats{Wins: 2, Draws: 1, Losses: 1}, wantScore: 62.5, wantWeight: 5},
+		{stats: MoveStats{Losses: 3}, wantScore: 0, wantWeight: 0},
+	}
+
+	for _, c := range cases {
+		// act
+		score, weight := c.stats.Score(), c.stats.Weight()
+
+		// assert
+		if score != c.wantScore || weight != c.wantWeight {
+			t.Errorf("%+v: want: %v/%d got: %v/%d", c.stats, c.wantScore, c.wantWeight, score, weight)
+		}
+	}
+}
+
+func TestBookBuilderPolyglot(t *testing.T) {
+	// arrange
+	bb := newTestBookBuilder(t, BookFilter{})
+
+	cases := []struct {
+		moves string
+		want  []BookMove
+	}{
+		{moves: "", want: []BookMove{{Move: "e2e4", Weight: 3}, {Move: "d2d4", Weight: 0}}},
+		{moves: "d2d4", want: []BookMove{{Move: "d7d5", Weight: 2}, {Move: "g8f6", Weight: 2}}},
+		{moves: "e2e4 e7e5 g1f3 b8c6 f1c4 g8f6", want: []BookMove{{Move: "e1g1", Weight: 2}}},
+	}
+
+	// act
+	var buf bytes.Buffer
+	if err := bb.Polyglot().Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	book, err := ReadPolyglotBook(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// assert
+	for _, c := range cases {
+		b := FENtoBoard(startPosFEN)
+		b.Moves(strings.Fields(c.moves)...)
+
+		got := book.Moves(&b)
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("'%s': want: %v got: %v", c.moves, c.want, got)
+		}
+	}
+}
+
+func TestBookBuilderWriteRepertoire(t *testing.T) {
+	// arrange
+	bb := newTestBookBuilder(t, BookFilter{MaxPly: 4})
+
+	cases := []struct {
+		moves string
+		want  []BookMove
+	}{
+		{moves: "", want: []BookMove{{Move: "d2d4", Weight: 0}, {Move: "e2e4", Weight: 3}}},
+		{moves: "e2e4", want: []BookMove{{Move: "c7c5", Weight: 1}, {Move: "e7e5", Weight: 0}}},
+		{moves: "e2e4 e7e5", want: []BookMove{{Move: "g1f3", Weight: 2}}},
+		{moves: "e2e4 e7e5 g1f3 b8c6", want: nil},
+	}
+
+	// act
+	var buf bytes.Buffer
+	if err := bb.WriteRepertoire(&buf); err != nil {
+		t.Fatal(err)
+	}
+	rep, err := ParseRepertoire(&buf)
+	if err != nil {
+		t.Fatalf("%v\n%s", err, buf.String())
+	}
+
+	// assert
+	for _, c := range cases {
+		b := FENtoBoard(startPosFEN)
+		b.Moves(strings.Fields(c.moves)...)
+
+		got := rep.Moves(&b)
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("'%s': want: %v got: %v", c.moves, c.want, got)
+		}
+	}
+}
@@ -0,0 +1,236 @@
+package uci
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// bookLearnFile is where the results of our book moves are kept between runs.
+const bookLearnFile = "book_learning.txt"
+
+// learnAdjudicateEval is how far ahead or behind, in centipawns, we have to be
+// in a game without a result for it to count as won or lost. Most games end by
+// resignation or on time, which the GUI doesn't tell us about.
+const learnAdjudicateEval = 300
+
+// learnWeightScale scales book weights up before learning adjusts them, so a
+// weight of 1 can still be made smaller.
+const learnWeightScale = 100
+
+// BookLearning holds the results of the games we played each book move in, so
+// moves which keep losing are played less and moves which win more.
+type BookLearning struct {
+	mtx       sync.Mutex
+	positions map[uint64]map[string]*MoveStats
+}
+
+// NewBookLearning returns an empty BookLearning.
+func NewBookLearning() *BookLearning {
+	return &BookLearning{positions: make(map[uint64]map[string]*MoveStats)}
+}
+
+// LoadBookLearning reads book learning from a file. A missing file is the
+// same as an empty one.
+func LoadBookLearning(path string) (*BookLearning, error) {
+	fp, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return NewBookLearning(), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer fp.Close()
+
+	l, err := ReadBookLearning(fp)
+	if err != nil {
+		return nil, fmt.Errorf("book learning '%s': %v", path, err)
+	}
+	return l, nil
+}
+
+// ReadBookLearning reads book learning, one move per line: the position's
+// Polyglot key in hex, the move in UCI notation, then the wins, draws and
+// losses of the side which played it, e.g.
+//
+//	463b96181691fc9c e2e4 3 1 2
+//
+// Blank lines and lines starting with '#' are skipped.
+func ReadBookLearning(r io.Reader) (*BookLearning, error) {
+	l := NewBookLearning()
+
+	s := bufio.NewScanner(r)
+	for n := 1; s.Scan(); n++ {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) != 5 {
+			return nil, fmt.Errorf("line %d: '%s' should be '<key> <move> <wins> <draws> <losses>'", n, line)
+		}
+
+		key, err := strconv.ParseUint(fields[0], 16, 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid key '%s'", n, fields[0])
+		}
+
+		var wdl [3]int
+		for i := range wdl {
+			if wdl[i], err = strconv.Atoi(fields[2+i]); err != nil || wdl[i] < 0 {
+				return nil, fmt.Errorf("line %d: invalid count '%s'", n, fields[2+i])
+			}
+		}
+
+		s := l.stats(key, fields[1])
+		s.Wins, s.Draws, s.Losses = wdl[0], wdl[1], wdl[2]
+	}
+
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+
+	return l, nil
+}
+
+// Write writes the book learning in the format ReadBookLearning reads, sorted
+// by key and move.
+func (l *BookLearning) Write(w io.Writer) error {
+	l.mtx.Lock()
+	var lines []string
+	for key, moves := range l.positions {
+		for move, s := range moves {
+			lines = append(lines, fmt.Sprintf("%016x %s %d %d %d", key, move, s.Wins, s.Draws, s.Losses))
+		}
+	}
+	l.mtx.Unlock()
+
+	sort.Strings(lines)
+
+	bw := bufio.NewWriter(w)
+	for _, line := range lines {
+		if _, err := bw.WriteString(line + "\n"); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// Save writes the book learning to a file, replacing it only once it's been
+// written in full.
+func (l *BookLearning) Save(path string) error {
+	tmp := path + ".tmp"
+	fp, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+
+	if err := l.Write(fp); err != nil {
+		_ = fp.Close()
+		return err
+	}
+	if err := fp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, path)
+}
+
+// stats returns the results of the move in the position, adding it if it's
+// new. The caller must hold mtx, or own l.
+func (l *BookLearning) stats(key uint64, move string) *MoveStats {
+	moves := l.positions[key]
+	if moves == nil {
+		moves = make(map[string]*MoveStats)
+		l.positions[key] = moves
+	}
+	s := moves[move]
+	if s == nil {
+		s = &MoveStats{Move: move}
+		moves[move] = s
+	}
+	return s
+}
+
+// Add records the result of a game, e.g. "1-0", for the move played in the
+// position b.
+func (l *BookLearning) Add(b *Board, move, result string) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	s := l.stats(b.Key(), move)
+	switch {
+	case result == "1/2-1/2":
+		s.Draws++
+	case (result == "1-0") == (b.ActiveColor == White):
+		s.Wins++
+	default:
+		s.Losses++
+	}
+}
+
+// Stats returns the results of the move in the position b.
+func (l *BookLearning) Stats(b *Board, move string) MoveStats {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	if s := l.positions[b.Key()][move]; s != nil {
+		return *s
+	}
+	return MoveStats{Move: move}
+}
+
+// Apply returns the book moves with their weights adjusted by how they've
+// scored. A move's weight, times learnWeightScale, is scaled by twice its score
+// with a win and a loss added,
+//
+//	2 * (wins + draws/2 + 1) / (games + 2)
+//
+// so a move without results keeps its weight, one which only wins approaches
+// double and one which only loses approaches nothing. Moves with a weight
+// never drop to 0, so they get a chance to recover. A nil l returns the moves
+// unchanged.
+func (l *BookLearning) Apply(b *Board, moves []BookMove) []BookMove {
+	if l == nil || len(moves) == 0 {
+		return moves
+	}
+
+	learned := make([]BookMove, len(moves))
+	for i, m := range moves {
+		s := l.Stats(b, m.Move)
+		factor := 2 * (float64(s.Wins) + float64(s.Draws)/2 + 1) / float64(s.Games()+2)
+
+		weight := int(math.Round(float64(m.Weight*learnWeightScale) * factor))
+		if m.Weight > 0 {
+			weight = max(weight, 1)
+		}
+		learned[i] = BookMove{Move: m.Move, Weight: weight}
+	}
+	return learned
+}
+
+// adjudicate returns the result of a game which ended without one from the
+// last eval, score or mate, from the point of view of the side ours: a mate or
+// learnAdjudicateEval centipawns either way decides it. It returns "*" unless
+// one side was clearly winning, and such games aren't learned from.
+func adjudicate(ours Color, score, mate int) string {
+	win, loss := "1-0", "0-1"
+	if ours == Black {
+		win, loss = loss, win
+	}
+
+	switch {
+	case mate > 0 || (mate == 0 && score >= learnAdjudicateEval):
+		return win
+	case mate < 0 || (mate == 0 && score <= -learnAdjudicateEval):
+		return loss
+	}
+	return "*"
+}
@@ -0,0 +1,161 @@
+package uci
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestBookLearningApply(t *testing.T) {
+	// arrange
+	moves := []BookMove{{Move: "e2e4", Weight: 2}, {Move: "d2d4", Weight: 1}, {Move: "g2g4", Weight: 0}}
+
+	cases := []struct {
+		name    string
+		learned []MoveStats
+		want    []BookMove
+	}{
+		{
+			name: "no results",
+			want: []BookMove{{Move: "e2e4", Weight: 200}, {Move: "d2d4", Weight: 100}, {Move: "g2g4", Weight: 0}},
+		},
+		{
+			name:    "wins and losses",
+			learned: []MoveStats{{Move: "e2e4", Losses: 3}, {Move: "d2d4", Wins: 1, Draws: 1}},
+			want:    []BookMove{{Move: "e2e4", Weight: 80}, {Move: "d2d4", Weight: 125}, {Move: "g2g4", Weight: 0}},
+		},
+		{
+			name:    "a weight never drops to 0",
+			learned: []MoveStats{{Move: "d2d4", Losses: 1000}, {Move: "g2g4", Wins: 10}},
+			want:    []BookMove{{Move: "e2e4", Weight: 200}, {Move: "d2d4", Weight: 1}, {Move: "g2g4", Weight: 0}},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			b := FENtoBoard(startPosFEN)
+			l := NewBookLearning()
+			for _, s := range c.learned {
+				*l.stats(b.Key(), s.Move) = s
+			}
+
+			// act
+			got := l.Apply(&b, moves)
+
+			// assert
+			if !reflect.DeepEqual(got, c.want) {
+				t.Errorf("want: %v got: %v", c.want, got)
+			}
+		})
+	}
+}
+
+func TestGameRecordLearn(t *testing.T) {
+	// arrange
+	var r gameRecord
+	start := FENtoBoard(startPosFEN)
+	r.setPosition(start, nil)
+	r.addBookMove(&start, "e2e4")
+
+	afterE5 := FENtoBoard(startPosFEN)
+	afterE5.Moves("e2e4", "e7e5")
+	r.setPosition(start, []string{"e2e4", "e7e5"})
+	r.addMove(&afterE5, "d1h5", "[%eval 0.10]")
+
+	afterNc6 := FENtoBoard(startPosFEN)
+	afterNc6.Moves("e2e4", "e7e5", "d1h5", "b8c6")
+	r.setPosition(start, []string{"e2e4", "e7e5", "d1h5", "b8c6"})
+	r.addBookMove(&afterNc6, "f1c4")
+
+	l := NewBookLearning()
+
+	// act
+	r.learn(l, "0-1")
+	r.learn(l, "1/2-1/2")
+
+	// assert
+	if got, want := l.Stats(&start, "e2e4"), (MoveStats{Move: "e2e4", Draws: 1, Losses: 1}); got != want {
+		t.Errorf("e4: want: %v got: %v", want, got)
+	}
+	if got, want := l.Stats(&afterNc6, "f1c4"), (MoveStats{Move: "f1c4", Draws: 1, Losses: 1}); got != want {
+		t.Errorf("Bc4: want: %v got: %v", want, got)
+	}
+	if got, want := l.Stats(&afterE5, "d1h5"), (MoveStats{Move: "d1h5"}); got != want {
+		t.Errorf("Qh5 isn't a book move: want: %v got: %v", want, got)
+	}
+}
+
+func TestReadBookLearning(t *testing.T) {
+	// arrange
+	cases := []struct {
+		name     string
+		text     string
+		want     string
+		expError string
+	}{
+		{
+			name: "round trip, sorted",
+			text: "# learned\n463b96181691fc9c e2e4 1 2 3\n\n463b96181691fc9c d2d4 0 0 1\n",
+			want: "463b96181691fc9c d2d4 0 0 1\n463b96181691fc9c e2e4 1 2 3\n",
+		},
+		{name: "fields", text: "463b96181691fc9c e2e4 1 2", expError: "line 1: '463b96181691fc9c e2e4 1 2' should be '<key> <move> <wins> <draws> <losses>'"},
+		{name: "key", text: "xyz e2e4 1 2 3", expError: "line 1: invalid key 'xyz'"},
+		{name: "count", text: "\n463b96181691fc9c e2e4 1 -2 3", expError: "line 2: invalid count '-2'"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			// act
+			l, err := ReadBookLearning(strings.NewReader(c.text))
+
+			// assert
+			if c.expError != "" {
+				if err == nil || err.Error() != c.expError {
+					t.Fatalf("want error: %s got: %v", c.expError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var buf bytes.Buffer
+			if err := l.Write(&buf); err != nil {
+				t.Fatal(err)
+			}
+			if buf.String() != c.want {
+				t.Errorf("want:\n%s\ngot:\n%s", c.want, buf.String())
+			}
+		})
+	}
+}
+
+func TestAdjudicate(t *testing.T) {
+	// arrange
+	cases := []struct {
+		ours  Color
+		score int
+		mate  int
+		want  string
+	}{
+		{ours: White, score: 300, want: "1-0"},
+		{ours: White, score: 299, want: "*"},
+		{ours: White, score: -300, want: "0-1"},
+		{ours: Black, score: 450, want: "0-1"},
+		{ours: Black, score: -450, want: "1-0"},
+		{ours: Black, mate: 3, want: "0-1"},
+		{ours: White, mate: -2, want: "0-1"},
+		{ours: Black, score: 0, want: "*"},
+	}
+
+	for _, c := range cases {
+		// act
+		got := adjudicate(c.ours, c.score, c.mate)
+
+		// assert
+		if got != c.want {
+			t.Errorf("%v score %d mate %d: want: %s got: %s", c.ours, c.score, c.mate, c.want, got)
+		}
+	}
+}
@@ -0,0 +1,217 @@
+package uci
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+)
+
+// calibrateWindow is how many centipawns worse than the best move a move can
+// be and still be played; its weight is what's left of the window.
+const calibrateWindow = 30
+
+// calibrateMateEval is the eval, in centipawns, given to a mate.
+const calibrateMateEval = 10_000
+
+// MoveEvals are a move's evals at each depth of a calibration search, in
+// centipawns for the side to move.
+type MoveEvals struct {
+	Move  string
+	Evals []int
+}
+
+// Avg returns the average eval.
+func (m MoveEvals) Avg() float64 {
+	if len(m.Evals) == 0 {
+		return 0
+	}
+	var sum float64
+	for _, e := range m.Evals {
+		sum += float64(e)
+	}
+	return sum / float64(len(m.Evals))
+}
+
+// AvgDev returns the average absolute deviation from the average eval.
+func (m MoveEvals) AvgDev() float64 {
+	if len(m.Evals) == 0 {
+		return 0
+	}
+	avg := m.Avg()
+	var sum float64
+	for _, e := range m.Evals {
+		sum += math.Abs(float64(e) - avg)
+	}
+	return sum / float64(len(m.Evals))
+}
+
+// GeoMean returns the geometric mean of the evals' sizes, signed like the
+// average, so a single deep outlier moves it less than the average. It's 0 if
+// any eval is.
+func (m MoveEvals) GeoMean() float64 {
+	if len(m.Evals) == 0 {
+		return 0
+	}
+	var sum float64
+	for _, e := range m.Evals {
+		if e == 0 {
+			return 0
+		}
+		sum += math.Log(math.Abs(float64(e)))
+	}
+	return math.Copysign(math.Exp(sum/float64(len(m.Evals))), m.Avg())
+}
+
+// StdDev returns the standard deviation of the evals.
+func (m MoveEvals) StdDev() float64 {
+	if len(m.Evals) == 0 {
+		return 0
+	}
+	avg := m.Avg()
+	var sum float64
+	for _, e := range m.Evals {
+		sum += (float64(e) - avg) * (float64(e) - avg)
+	}
+	return math.Sqrt(sum / float64(len(m.Evals)))
+}
+
+// Weight returns the move's book weight: the part of calibrateWindow left
+// after how far its average is below best, the best move's average.
+func (m MoveEvals) Weight(best float64) int {
+	return max(0, int(math.Round(calibrateWindow-(best-m.Avg()))))
+}
+
+// Calibrate searches the position to maxDepth with every legal move as a PV
+// and returns each move's evals from minDepth on, best average first. sf must
+// have been sent 'uci'.
+func Calibrate(ctx context.Context, sf Engine, b Board, minDepth, maxDepth int) ([]MoveEvals, error) {
+	legal := b.GenerateMoves()
+	if len(legal) == 0 {
+		return nil, fmt.Errorf("'%s' has no legal moves", b.FEN())
+	}
+
+	sf.Write(fmt.Sprintf("setoption name MultiPV value %d", len(legal)))
+	sf.Write("ucinewgame")
+	sf.Write("isready")
+	if err := waitFor(ctx, sf, "readyok", nil); err != nil {
+		return nil, err
+	}
+
+	sf.Write(fmt.Sprintf("position fen %s", b.FEN()))
+	sf.Write(fmt.Sprintf("go depth %d", maxDepth))
+
+	// depths holds the last exact eval of each move at each depth
+	depths := make(map[int]map[string]int)
+	err := waitFor(ctx, sf, "bestmove", func(line string) {
+		parts := strings.Fields(line)
+		if len(parts) < 2 || parts[0] != "info" || parts[1] == "string" ||
+			strings.Contains(line, "lowerbound") || strings.Contains(line, "upperbound") {
+			return
+		}
+
+		info := parseInfo(parts, func(string) {})
+		if info.PV == "" || info.Depth < minDepth {
+			return
+		}
+
+		eval := info.Score
+		if info.Mate > 0 {
+			eval = calibrateMateEval
+		} else if info.Mate < 0 {
+			eval = -calibrateMateEval
+		}
+
+		if depths[info.Depth] == nil {
+			depths[info.Depth] = make(map[string]int)
+		}
+		depths[info.Depth][strings.Fields(info.PV)[0]] = eval
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var evals []MoveEvals
+	for _, m := range legal {
+		me := MoveEvals{Move: m.String()}
+		for depth := minDepth; depth <= maxDepth; depth++ {
+			if eval, ok := depths[depth][me.Move]; ok {
+				me.Evals = append(me.Evals, eval)
+			}
+		}
+		if len(me.Evals) != 0 {
+			evals = append(evals, me)
+		}
+	}
+
+	sort.SliceStable(evals, func(i, j int) bool {
+		return evals[i].Avg() > evals[j].Avg()
+	})
+
+	return evals, nil
+}
+
+// waitFor reads Stockfish's output until a line starting with prefix, passing
+// each line before it to visit, which may be nil.
+func waitFor(ctx context.Context, sf Engine, prefix string, visit func(line string)) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case line, ok := <-sf.Output():
+			if !ok {
+				return fmt.Errorf("stockfish exited waiting for '%s'", prefix)
+			}
+			line = strings.TrimSpace(line)
+			if strings.HasPrefix(line, prefix) {
+				return nil
+			}
+			if visit != nil {
+				visit(line)
+			}
+		}
+	}
+}
+
+// WriteCalibration writes the evals of the moves in the position b as
+// firstMove entries for openingBook.go, after a table of their stats. Evals
+// are in pawns for the side to move.
+func WriteCalibration(w io.Writer, b Board, evals []MoveEvals, minDepth, maxDepth int, indent string) error {
+	var sb strings.Builder
+	line := func(format string, a ...interface{}) {
+		if s := strings.TrimRight(fmt.Sprintf(format, a...), " "); s != "" {
+			sb.WriteString(indent + s)
+		}
+		sb.WriteString("\n")
+	}
+
+	position := "the starting position"
+	if b.FEN() != startPosFEN {
+		position = fmt.Sprintf("'%s'", b.FEN())
+	}
+	line("// stats from Stockfish depths %d-%d of %s", minDepth, maxDepth, position)
+	line("")
+	line("// move  avg dev    avg  geometric mean  stddev")
+	for _, m := range evals {
+		line("// %-6s %6.2f %6.2f %15.2f %7.2f", b.sanOrUCI(m.Move), m.AvgDev(), m.Avg(), m.GeoMean(), m.StdDev())
+	}
+	line("")
+
+	var best float64
+	if len(evals) != 0 {
+		best = evals[0].Avg()
+	}
+	for _, m := range evals {
+		moveNumber := fmt.Sprintf("%d.", b.FullMove)
+		if b.ActiveColor == Black {
+			moveNumber = fmt.Sprintf("%d...", b.FullMove)
+		}
+		entry := fmt.Sprintf("{uci: %q, freq: %d},", m.Move, m.Weight(best))
+		line("%-28s // %s %-5s %+0.2f", entry, moveNumber, b.sanOrUCI(m.Move), m.Avg()/100)
+	}
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
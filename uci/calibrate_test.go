@@ -0,0 +1,111 @@
+package uci
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestMoveEvals(t *testing.T) {
+	// arrange
+	cases := []struct {
+		evals      []int
+		wantAvg    string
+		wantAvgDev string
+		wantGeo    string
+		wantStdDev string
+		wantWeight int
+	}{
+		{evals: []int{20, 30, 40}, wantAvg: "30.00", wantAvgDev: "6.67", wantGeo: "28.84", wantStdDev: "8.16", wantWeight: 25},
+		{evals: []int{-10, -20}, wantAvg: "-15.00", wantAvgDev: "5.00", wantGeo: "-14.14", wantStdDev: "5.00", wantWeight: 0},
+		{evals: []int{0, 10}, wantAvg: "5.00", wantAvgDev: "5.00", wantGeo: "0.00", wantStdDev: "5.00", wantWeight: 0},
+		{evals: []int{35, 35}, wantAvg: "35.00", wantAvgDev: "0.00", wantGeo: "35.00", wantStdDev: "0.00", wantWeight: 30},
+		{evals: nil, wantAvg: "0.00", wantAvgDev: "0.00", wantGeo: "0.00", wantStdDev: "0.00", wantWeight: 0},
+	}
+
+	for _, c := range cases {
+		t.Run(fmt.Sprint(c.evals), func(t *testing.T) {
+			m := MoveEvals{Move: "e2e4", Evals: c.evals}
+
+			// act
+			got := []string{
+				fmt.Sprintf("%0.2f", m.Avg()),
+				fmt.Sprintf("%0.2f", m.AvgDev()),
+				fmt.Sprintf("%0.2f", m.GeoMean()),
+				fmt.Sprintf("%0.2f", m.StdDev()),
+			}
+			weight := m.Weight(35)
+
+			// assert
+			want := []string{c.wantAvg, c.wantAvgDev, c.wantGeo, c.wantStdDev}
+			if strings.Join(got, " ") != strings.Join(want, " ") {
+				t.Errorf("avg/avg dev/geometric mean/stddev want: %v got: %v", want, got)
+			}
+			if weight != c.wantWeight {
+				t.Errorf("weight want: %d got: %d", c.wantWeight, weight)
+			}
+		})
+	}
+}
+
+func TestWriteCalibration(t *testing.T) {
+	// arrange
+	cases := []struct {
+		name  string
+		moves string
+		evals []MoveEvals
+		want  string
+	}{
+		{
+			name: "first moves",
+			evals: []MoveEvals{
+				{Move: "e2e4", Evals: []int{25, 33}},
+				{Move: "g1f3", Evals: []int{20, 22}},
+				{Move: "g2g4", Evals: []int{-130, -140}},
+			},
+			want: `	// stats from Stockfish depths 35-36 of the starting position
+
+	// move  avg dev    avg  geometric mean  stddev
+	// e4       4.00  29.00           28.72    4.00
+	// Nf3      1.00  21.00           20.98    1.00
+	// g4       5.00 -135.00         -134.91    5.00
+
+	{uci: "e2e4", freq: 30},     // 1. e4    +0.29
+	{uci: "g1f3", freq: 22},     // 1. Nf3   +0.21
+	{uci: "g2g4", freq: 0},      // 1. g4    -1.35
+`,
+		},
+		{
+			name:  "replies",
+			moves: "e2e4",
+			evals: []MoveEvals{{Move: "c7c5", Evals: []int{-30, -30}}},
+			want: `	// stats from Stockfish depths 35-36 of 'rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1'
+
+	// move  avg dev    avg  geometric mean  stddev
+	// c5       0.00 -30.00          -30.00    0.00
+
+	{uci: "c7c5", freq: 30},     // 1... c5    -0.30
+`,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			b := FENtoBoard(startPosFEN)
+			b.Moves(strings.Fields(c.moves)...)
+
+			var sb strings.Builder
+
+			// act
+			err := WriteCalibration(&sb, b, c.evals, 35, 36, "\t")
+
+			// assert
+			if err != nil {
+				t.Fatal(err)
+			}
+			if sb.String() != c.want {
+				t.Errorf("want:\n%s\ngot:\n%s", c.want, sb.String())
+			}
+		})
+	}
+}
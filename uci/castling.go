@@ -0,0 +1,199 @@
+package uci
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// castling rights, in the same order as the Polyglot castling keys
+const (
+	castleWhiteKing = iota
+	castleWhiteQueen
+	castleBlackKing
+	castleBlackQueen
+)
+
+// castlingRooks holds the file of the castling rook for each right, or -1 if
+// the right is gone.
+type castlingRooks [4]int
+
+var noCastling = castlingRooks{-1, -1, -1, -1}
+
+func (r castlingRooks) key() uint64 {
+	return castlingKey(r[castleWhiteKing] != -1, r[castleWhiteQueen] != -1, r[castleBlackKing] != -1, r[castleBlackQueen] != -1)
+}
+
+// castlingRank returns the back rank (0 or 7) for a castling right.
+func castlingRank(right int) int {
+	if right < castleBlackKing {
+		return 0
+	}
+	return 7
+}
+
+// parseCastling reads standard (KQkq), X-FEN and Shredder-FEN (HAha) castling
+// rights. K and Q refer to the outermost rook on that side of the king, a file
+// letter to the rook on that file.
+func (b *Board) parseCastling(castling string) (castlingRooks, error) {
+	rooks := noCastling
+	if castling == "-" {
+		return rooks, nil
+	}
+
+	for _, c := range castling {
+		color, rank, king, rook := castleWhiteKing, 0, WhiteKing, WhiteRook
+		if unicode.IsLower(c) {
+			color, rank, king, rook = castleBlackKing, 7, BlackKing, BlackRook
+		}
+
+		kingFile := -1
+		for f := 0; f < 8; f++ {
+			if b.squares[makeSquare(f, rank)] == king {
+				kingFile = f
+			}
+		}
+
+		side, file := 0, -1
+		switch upper := unicode.ToUpper(c); {
+		case upper == 'K':
+			for f := 7; f > kingFile && file == -1; f-- {
+				if b.squares[makeSquare(f, rank)] == rook {
+					file = f
+				}
+			}
+		case upper == 'Q':
+			side = 1
+			for f := 0; f < kingFile && file == -1; f++ {
+				if b.squares[makeSquare(f, rank)] == rook {
+					file = f
+				}
+			}
+		case upper >= 'A' && upper <= 'H':
+			file = int(upper - 'A')
+			if file < kingFile {
+				side = 1
+			}
+			if b.squares[makeSquare(file, rank)] != rook || file == kingFile {
+				file = -1
+			}
+		default:
+			return noCastling, fmt.Errorf("castling '%s' has unknown right '%c'", castling, c)
+		}
+
+		if kingFile == -1 {
+			return noCastling, fmt.Errorf("castling right '%c' needs %s on rank %d", c, king, rank+1)
+		}
+		if file == -1 {
+			return noCastling, fmt.Errorf("castling right '%c' has no %s to castle with", c, rook)
+		}
+		if rooks[color+side] != -1 {
+			return noCastling, fmt.Errorf("castling '%s' repeats '%c'", castling, c)
+		}
+
+		rooks[color+side] = file
+	}
+
+	return rooks, nil
+}
+
+// formatCastling writes the castling rights in X-FEN: K and Q (k and q) when
+// the castling rook is the outermost rook on its side of the king, otherwise
+// the rook's file. For standard chess this is always KQkq.
+func (b *Board) formatCastling(rooks castlingRooks) string {
+	var sb strings.Builder
+	for right, file := range rooks {
+		if file == -1 {
+			continue
+		}
+
+		rank, rook := castlingRank(right), WhiteRook
+		if right >= castleBlackKing {
+			rook = BlackRook
+		}
+
+		kingSide := right%2 == 0
+		outermost := true
+		for f := file; ; {
+			if kingSide {
+				f++
+			} else {
+				f--
+			}
+			if f < 0 || f > 7 {
+				break
+			}
+			if b.squares[makeSquare(f, rank)] == rook {
+				outermost = false
+			}
+		}
+
+		var c rune
+		switch {
+		case outermost && kingSide:
+			c = 'K'
+		case outermost:
+			c = 'Q'
+		default:
+			c = rune('A' + file)
+		}
+		if rook == BlackRook {
+			c = unicode.ToLower(c)
+		}
+		sb.WriteRune(c)
+	}
+
+	if sb.Len() == 0 {
+		return "-"
+	}
+	return sb.String()
+}
+
+// castlingSide returns the castling right used if the king move from -> to is
+// castling, or -1. Castling is written as the king taking its own rook
+// (Chess960) or, when Chess960 is off, as the king moving two squares from
+// the e-file.
+func (b *Board) castlingSide(from, to Square, rooks castlingRooks) int {
+	color := castleWhiteKing
+	switch b.squares[from] {
+	case WhiteKing:
+	case BlackKing:
+		color = castleBlackKing
+	default:
+		return -1
+	}
+
+	rank := castlingRank(color)
+	if from.Rank() != rank || to.Rank() != rank {
+		return -1
+	}
+
+	for side := 0; side < 2; side++ {
+		if file := rooks[color+side]; file != -1 && to == makeSquare(file, rank) {
+			return color + side
+		}
+	}
+
+	if !b.Chess960 && from.File() == 4 {
+		switch to.File() {
+		case 6:
+			if rooks[color] != -1 {
+				return color
+			}
+		case 2:
+			if rooks[color+1] != -1 {
+				return color + 1
+			}
+		}
+	}
+
+	return -1
+}
+
+// castlingTargets returns the files the king and rook end up on.
+func castlingTargets(right int) (kingFile, rookFile int) {
+	if right%2 == 0 {
+		return 6, 5
+	}
+	return 2, 3
+}
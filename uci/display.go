@@ -0,0 +1,53 @@
+package uci
+
+import (
+	"fmt"
+	"strings"
+)
+
+// unicodePieces is indexed by Piece, like pieceRunes.
+var unicodePieces = []rune(" ♙♘♗♖♕♔♟♞♝♜♛♚")
+
+// Display returns the board drawn the way Stockfish's 'd' command does,
+// followed by the FEN, position key and checkers. unicode draws the pieces
+// as chess symbols instead of FEN letters.
+func (b *Board) Display(unicode bool) []string {
+	const border = " +---+---+---+---+---+---+---+---+"
+
+	lines := make([]string, 0, 24)
+	lines = append(lines, border)
+	for rank := 7; rank >= 0; rank-- {
+		var line strings.Builder
+		line.WriteString(" |")
+		for file := 0; file < 8; file++ {
+			p := b.squares[makeSquare(file, rank)]
+			r := p.rune()
+			if unicode {
+				r = unicodePieces[p]
+			}
+			line.WriteString(fmt.Sprintf(" %c |", r))
+		}
+		line.WriteString(fmt.Sprintf(" %d", rank+1))
+		lines = append(lines, line.String(), border)
+	}
+	lines = append(lines, "   a   b   c   d   e   f   g   h", "")
+
+	var checkers []string
+	for _, sq := range b.Checkers().Squares() {
+		checkers = append(checkers, sq.String())
+	}
+
+	side := "white"
+	if b.ActiveColor == Black {
+		side = "black"
+	}
+
+	lines = append(lines,
+		fmt.Sprintf("Fen: %s", b.FEN()),
+		fmt.Sprintf("Key: %016X", b.Key()),
+		fmt.Sprintf("Side to move: %s", side),
+		fmt.Sprintf("Checkers: %s", strings.Join(checkers, " ")),
+	)
+
+	return lines
+}
@@ -0,0 +1,95 @@
+package uci
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDisplay(t *testing.T) {
+	// arrange
+	cases := []struct {
+		name    string
+		fen     string
+		unicode bool
+		want    []string
+	}{
+		{
+			name: "start position",
+			fen:  startPosFEN,
+			want: []string{
+				" +---+---+---+---+---+---+---+---+",
+				" | r | n | b | q | k | b | n | r | 8",
+				" +---+---+---+---+---+---+---+---+",
+				" | p | p | p | p | p | p | p | p | 7",
+				" +---+---+---+---+---+---+---+---+",
+				" |   |   |   |   |   |   |   |   | 6",
+				" +---+---+---+---+---+---+---+---+",
+				" |   |   |   |   |   |   |   |   | 5",
+				" +---+---+---+---+---+---+---+---+",
+				" |   |   |   |   |   |   |   |   | 4",
+				" +---+---+---+---+---+---+---+---+",
+				" |   |   |   |   |   |   |   |   | 3",
+				" +---+---+---+---+---+---+---+---+",
+				" | P | P | P | P | P | P | P | P | 2",
+				" +---+---+---+---+---+---+---+---+",
+				" | R | N | B | Q | K | B | N | R | 1",
+				" +---+---+---+---+---+---+---+---+",
+				"   a   b   c   d   e   f   g   h",
+				"",
+				"Fen: rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1",
+				"Key: 463B96181691FC9C",
+				"Side to move: white",
+				"Checkers: ",
+			},
+		},
+		{
+			name:    "double check, unicode",
+			fen:     "4k3/8/8/1B6/8/8/8/4R1K1 b - - 0 1",
+			unicode: true,
+			want: []string{
+				" +---+---+---+---+---+---+---+---+",
+				" |   |   |   |   | ♚ |   |   |   | 8",
+				" +---+---+---+---+---+---+---+---+",
+				" |   |   |   |   |   |   |   |   | 7",
+				" +---+---+---+---+---+---+---+---+",
+				" |   |   |   |   |   |   |   |   | 6",
+				" +---+---+---+---+---+---+---+---+",
+				" |   | ♗ |   |   |   |   |   |   | 5",
+				" +---+---+---+---+---+---+---+---+",
+				" |   |   |   |   |   |   |   |   | 4",
+				" +---+---+---+---+---+---+---+---+",
+				" |   |   |   |   |   |   |   |   | 3",
+				" +---+---+---+---+---+---+---+---+",
+				" |   |   |   |   |   |   |   |   | 2",
+				" +---+---+---+---+---+---+---+---+",
+				" |   |   |   |   | ♖ |   | ♔ |   | 1",
+				" +---+---+---+---+---+---+---+---+",
+				"   a   b   c   d   e   f   g   h",
+				"",
+				"Fen: 4k3/8/8/1B6/8/8/8/4R1K1 b - - 0 1",
+				"Key: 33D8EAF23D4B66EC",
+				"Side to move: black",
+				"Checkers: e1 b5",
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			b := FENtoBoard(c.fen)
+
+			// act
+			got := b.Display(c.unicode)
+
+			// assert
+			if len(got) != len(c.want) {
+				t.Fatalf("want %d lines, got %d:\n%s", len(c.want), len(got), strings.Join(got, "\n"))
+			}
+			for i := range got {
+				if got[i] != c.want[i] {
+					t.Errorf("line %d\nwant: '%s'\ngot:  '%s'", i, c.want[i], got[i])
+				}
+			}
+		})
+	}
+}
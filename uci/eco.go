@@ -0,0 +1,100 @@
+package uci
+
+import (
+	"bufio"
+	_ "embed"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+// ecoTSV is the ECO table: code, name and moves, tab separated, in the
+// format of lichess' chess-openings.
+//
+//go:embed eco.tsv
+var ecoTSV string
+
+// ecoOpenings is ecoTSV by position key, so an opening is named however it
+// was reached. It's parsed on first use, since parsing needs the move
+// generator's tables, which are set up in init.
+var (
+	ecoOpenings     map[uint64]Opening
+	ecoOpeningsOnce sync.Once
+)
+
+// Opening is a named opening position.
+type Opening struct {
+	ECO  string
+	Name string
+}
+
+func (o Opening) String() string {
+	return o.ECO + " " + o.Name
+}
+
+// ParseECO reads an ECO table by position key. A header line starting with
+// 'eco' is skipped. A position which is named twice keeps its first name.
+func ParseECO(r io.Reader) (map[uint64]Opening, error) {
+	openings := make(map[uint64]Opening)
+
+	s := bufio.NewScanner(r)
+	for n := 1; s.Scan(); n++ {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || (n == 1 && strings.HasPrefix(line, "eco\t")) {
+			continue
+		}
+
+		fields := strings.Split(line, "\t")
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("line %d: '%s' should be '<eco>\\t<name>\\t<moves>'", n, line)
+		}
+
+		b := FENtoBoard(startPosFEN)
+		for _, word := range strings.Fields(fields[2]) {
+			san := stripMoveNumber(word)
+			if san == "" {
+				continue
+			}
+			move, err := b.ParseSAN(san)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %v", n, err)
+			}
+			b.Moves(move)
+		}
+
+		if _, ok := openings[b.Key()]; !ok {
+			openings[b.Key()] = Opening{ECO: fields[0], Name: fields[1]}
+		}
+	}
+
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+
+	return openings, nil
+}
+
+// mustParseECO parses an ECO table which is known to be valid.
+func mustParseECO(s string) map[uint64]Opening {
+	openings, err := ParseECO(strings.NewReader(s))
+	if err != nil {
+		panic(err)
+	}
+	return openings
+}
+
+// ClassifyOpening returns the opening of the last named position in the
+// history, and false if none of them are named.
+func ClassifyOpening(history History) (Opening, bool) {
+	ecoOpeningsOnce.Do(func() {
+		ecoOpenings = mustParseECO(ecoTSV)
+	})
+
+	for i := len(history) - 1; i >= 0; i-- {
+		if o, ok := ecoOpenings[history[i]]; ok {
+			return o, true
+		}
+	}
+	return Opening{}, false
+}
@@ -0,0 +1,83 @@
+package uci
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestClassifyOpening(t *testing.T) {
+	// arrange
+	cases := []struct {
+		name   string
+		moves  string
+		want   string
+		wantOK bool
+	}{
+		{name: "start position", moves: "", wantOK: false},
+		{name: "first move", moves: "e2e4", want: "B00 King's Pawn Game", wantOK: true},
+		{name: "Englund Gambit", moves: "d2d4 e7e5 d4e5 b8c6", want: "A40 Englund Gambit Complex", wantOK: true},
+		{name: "Englund Gambit by transposition", moves: "d2d4 e7e5 d4e5 b8c6 c1f4 d8e7 g1f3", want: "A40 Englund Gambit Complex", wantOK: true},
+		{name: "named position transposed into", moves: "g1f3 b8c6 d2d4 e7e5 d4e5 d8e7", want: "A40 Englund Gambit Complex: Englund Gambit", wantOK: true},
+		{name: "deepest named position", moves: "e2e4 c7c5 d2d4 c5d4 c2c3 d4c3 b1c3 d7d6 f1c4", want: "B21 Sicilian Defense: Smith-Morra Gambit Accepted", wantOK: true},
+		{name: "castling", moves: "e2e4 e7e5 g1f3 b8c6 f1b5 a7a6 b5a4 g8f6 e1g1 f8e7", want: "C84 Ruy Lopez: Closed", wantOK: true},
+		{name: "queen's indian", moves: "d2d4 g8f6 g1f3 e7e6 c2c4 b7b6 g2g3 c8a6", want: "E15 Queen's Indian Defense: Fianchetto Variation, Nimzowitsch Variation", wantOK: true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			b := FENtoBoard(startPosFEN)
+			history := History{b.Key()}
+			for _, move := range strings.Fields(c.moves) {
+				b.Moves(move)
+				history = append(history, b.Key())
+			}
+
+			// act
+			got, ok := ClassifyOpening(history)
+
+			// assert
+			if ok != c.wantOK || (ok && got.String() != c.want) {
+				t.Errorf("want: '%s' %v got: '%s' %v", c.want, c.wantOK, got, ok)
+			}
+		})
+	}
+}
+
+func TestParseECO(t *testing.T) {
+	// arrange
+	cases := []struct {
+		name     string
+		text     string
+		expError string
+	}{
+		{name: "header and duplicate", text: "eco\tname\tpgn\nC20\tKing's Pawn Game\t1. e4 e5\nC20\tAgain\t1. e4 e5\n"},
+		{name: "fields", text: "C20\tKing's Pawn Game", expError: "line 1: 'C20\tKing's Pawn Game' should be '<eco>\\t<name>\\t<moves>'"},
+		{name: "illegal move", text: "eco\tname\tpgn\nC20\tKing's Pawn Game\t1. e5", expError: "line 2: move 'e5' is not legal in 'rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1'"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			// act
+			openings, err := ParseECO(strings.NewReader(c.text))
+
+			// assert
+			if c.expError != "" {
+				if err == nil || err.Error() != c.expError {
+					t.Fatalf("want error: %s got: %v", c.expError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(openings) != 1 {
+				t.Fatalf("want 1 opening got: %v", openings)
+			}
+			for _, o := range openings {
+				if o.Name != "King's Pawn Game" {
+					t.Errorf("want the first name got: %s", o.Name)
+				}
+			}
+		})
+	}
+}
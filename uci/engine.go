@@ -0,0 +1,36 @@
+package uci
+
+import (
+	"context"
+
+	"trollfish/stockfish"
+)
+
+// Engine is the UCI engine trollfish proxies, normally Stockfish, see
+// StartStockfish.
+type Engine interface {
+	// Write sends a command to the engine.
+	Write(s string)
+
+	// Output returns the engine's output, one line at a time.
+	Output() <-chan string
+
+	// Quit stops the engine.
+	Quit()
+}
+
+// StartStockfish starts the Stockfish binary trollfish proxies.
+func StartStockfish(ctx context.Context, logInfo func(string)) (Engine, error) {
+	sf, err := stockfish.Start(ctx, stockfishPath, logInfo)
+	if err != nil {
+		return nil, err
+	}
+	return sf, nil
+}
+
+// startEngine makes e the engine the proxy talks to and starts reading its
+// output.
+func (u *UCI) startEngine(e Engine) {
+	u.sf = e
+	go u.stockFishReadLoop()
+}
@@ -0,0 +1,185 @@
+package uci
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeEngine is a scripted Engine. It answers 'uci' and 'isready' the way
+// Stockfish does, and each 'go' with the next of its transcripts of 'info' and
+// 'bestmove' lines, so the proxy can be tested without Stockfish. A "stop"
+// line in a transcript holds the rest of it until 'stop' is sent.
+type fakeEngine struct {
+	mtx         sync.Mutex
+	transcripts [][]string
+	stopped     []string
+	commands    []string
+	output      chan string
+	quit        bool
+}
+
+func newFakeEngine(transcripts ...[]string) *fakeEngine {
+	return &fakeEngine{
+		transcripts: transcripts,
+		output:      make(chan string, 512),
+	}
+}
+
+func (e *fakeEngine) Write(s string) {
+	e.mtx.Lock()
+	defer e.mtx.Unlock()
+
+	if e.quit {
+		return
+	}
+
+	e.commands = append(e.commands, s)
+
+	switch cmd := strings.Fields(s); {
+	case len(cmd) == 0:
+	case cmd[0] == "uci":
+		e.output <- "id name Fakefish"
+		e.output <- "uciok"
+	case cmd[0] == "isready":
+		e.output <- "readyok"
+	case cmd[0] == "go" && len(e.transcripts) != 0:
+		e.replay(e.transcripts[0])
+		e.transcripts = e.transcripts[1:]
+	case cmd[0] == "stop":
+		lines := e.stopped
+		e.stopped = nil
+		e.replay(lines)
+	}
+}
+
+// replay writes the transcript's lines up to a "stop", keeping the rest for
+// the 'stop' command. The caller must hold mtx.
+func (e *fakeEngine) replay(transcript []string) {
+	for i, line := range transcript {
+		if line == "stop" {
+			e.stopped = transcript[i+1:]
+			return
+		}
+		e.output <- line
+	}
+}
+
+func (e *fakeEngine) Output() <-chan string {
+	return e.output
+}
+
+func (e *fakeEngine) Quit() {
+	e.mtx.Lock()
+	defer e.mtx.Unlock()
+
+	if !e.quit {
+		e.quit = true
+		close(e.output)
+	}
+}
+
+// sent returns the commands written to the engine starting with prefix.
+func (e *fakeEngine) sent(prefix string) []string {
+	e.mtx.Lock()
+	defer e.mtx.Unlock()
+
+	var v []string
+	for _, cmd := range e.commands {
+		if strings.HasPrefix(cmd, prefix) {
+			v = append(v, cmd)
+		}
+	}
+	return v
+}
+
+// lineWriter is the proxy's stdout for tests, one line at a time.
+type lineWriter chan string
+
+func (w lineWriter) Write(p []byte) (int, error) {
+	for _, line := range strings.Split(strings.TrimSuffix(string(p), "\n"), "\n") {
+		w <- line
+	}
+	return len(p), nil
+}
+
+// newFakeProxy returns a proxy talking to the engine, and its output.
+func newFakeProxy(t *testing.T, e *fakeEngine) (*UCI, lineWriter) {
+	t.Helper()
+
+	out := make(lineWriter, 512)
+
+	u := New("trollfish", "test")
+	u.log = nopWriteCloser{Writer: &strings.Builder{}}
+	u.out = out
+	u.recordGames = false
+	u.startEngine(e)
+
+	t.Cleanup(e.Quit)
+
+	return u, out
+}
+
+// waitLine returns the next line the proxy writes starting with prefix.
+func waitLine(t *testing.T, out lineWriter, prefix string) string {
+	t.Helper()
+
+	lines := waitLines(t, out, prefix)
+	return lines[len(lines)-1]
+}
+
+// waitLines returns the lines the proxy writes up to and including the next
+// line starting with prefix.
+func waitLines(t *testing.T, out lineWriter, prefix string) []string {
+	t.Helper()
+
+	var lines []string
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case line := <-out:
+			lines = append(lines, line)
+			if strings.HasPrefix(line, prefix) {
+				return lines
+			}
+		case <-timeout:
+			t.Fatalf("timed out waiting for '%s'", prefix)
+			return nil
+		}
+	}
+}
+
+func TestFakeEngine(t *testing.T) {
+	// arrange
+	e := newFakeEngine(
+		[]string{"info depth 1 score cp 20 pv e2e4", "bestmove e2e4"},
+		[]string{"info depth 1 score cp 30 pv d2d4", "stop", "bestmove d2d4"},
+	)
+
+	// act
+	e.Write("isready")
+	e.Write("go depth 1")
+	e.Write("go infinite")
+	e.Write("stop")
+	e.Write("go depth 1")
+	e.Quit()
+	e.Write("isready")
+
+	// assert
+	var got []string
+	for line := range e.Output() {
+		got = append(got, line)
+	}
+	want := []string{
+		"readyok",
+		"info depth 1 score cp 20 pv e2e4", "bestmove e2e4",
+		"info depth 1 score cp 30 pv d2d4", "bestmove d2d4",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("want output %q got: %q", want, got)
+	}
+	if n := len(e.sent("go ")); n != 3 {
+		t.Errorf("want 3 'go' commands got: %d", n)
+	}
+}
@@ -0,0 +1,184 @@
+package uci
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// EPD is a position from an Extended Position Description file, as used by
+// test suites: a FEN without the move counters followed by operations such as
+// 'bm Nf3; id "WAC.001";'.
+type EPD struct {
+	Board Board
+
+	// ID is the 'id' operation, naming the position.
+	ID string
+
+	// Comment is the 'c0' operation.
+	Comment string
+
+	// BestMoves and AvoidMoves are the 'bm' and 'am' operations in UCI
+	// notation.
+	BestMoves  []string
+	AvoidMoves []string
+
+	// Ops holds every operation by opcode, with the operands as written.
+	Ops map[string][]string
+}
+
+// ReadEPD reads an EPD file, one position per line. Blank lines and lines
+// starting with '#' are skipped.
+func ReadEPD(r io.Reader) ([]EPD, error) {
+	var positions []EPD
+
+	s := bufio.NewScanner(r)
+	for n := 1; s.Scan(); n++ {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		e, err := ParseEPD(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", n, err)
+		}
+		positions = append(positions, e)
+	}
+
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+
+	return positions, nil
+}
+
+// ParseEPD parses a single EPD line. The 'hmvc' and 'fmvn' operations set the
+// move counters; 'bm' and 'am' moves in SAN or UCI notation must be legal.
+func ParseEPD(line string) (EPD, error) {
+	fields := strings.Fields(line)
+	if len(fields) < 4 {
+		return EPD{}, fmt.Errorf("epd '%s' has %d fields, want at least 4", line, len(fields))
+	}
+
+	b, err := ParseFEN(strings.Join(fields[:4], " "))
+	if err != nil {
+		return EPD{}, err
+	}
+
+	// the operations start after the fourth field; strings.Fields dropped
+	// the separators so find the field again
+	rest := line
+	for i := 0; i < 4; i++ {
+		rest = strings.TrimLeftFunc(rest, unicode.IsSpace)
+		rest = rest[len(fields[i]):]
+	}
+
+	e := EPD{Board: b, Ops: make(map[string][]string)}
+
+	for _, op := range splitEPDOps(rest) {
+		if len(op) == 0 {
+			continue
+		}
+		opcode, operands := op[0], op[1:]
+		e.Ops[opcode] = operands
+
+		switch opcode {
+		case "id":
+			e.ID = strings.Join(operands, " ")
+		case "c0":
+			e.Comment = strings.Join(operands, " ")
+		case "bm", "am":
+			moves, err := epdMoves(&e.Board, operands)
+			if err != nil {
+				return EPD{}, fmt.Errorf("epd '%s' opcode '%s': %v", line, opcode, err)
+			}
+			if opcode == "bm" {
+				e.BestMoves = moves
+			} else {
+				e.AvoidMoves = moves
+			}
+		case "hmvc", "fmvn":
+			if len(operands) != 1 {
+				return EPD{}, fmt.Errorf("epd '%s' opcode '%s' wants one operand", line, opcode)
+			}
+			n, err := strconv.Atoi(operands[0])
+			if err != nil || n < 0 || (opcode == "fmvn" && n < 1) {
+				return EPD{}, fmt.Errorf("epd '%s' opcode '%s' operand '%s' is invalid", line, opcode, operands[0])
+			}
+			if opcode == "hmvc" {
+				e.Board.HalfmoveClock = n
+			} else {
+				e.Board.FullMove = n
+			}
+		}
+	}
+
+	return e, nil
+}
+
+// epdMoves returns the moves in UCI notation.
+func epdMoves(b *Board, moves []string) ([]string, error) {
+	uciMoves := make([]string, 0, len(moves))
+	for _, move := range moves {
+		if b.IsLegalMove(move) {
+			uciMoves = append(uciMoves, move)
+			continue
+		}
+		uciMove, err := b.ParseSAN(move)
+		if err != nil {
+			return nil, err
+		}
+		uciMoves = append(uciMoves, uciMove)
+	}
+	return uciMoves, nil
+}
+
+// splitEPDOps splits the operations on ';' into the opcode and its operands.
+// Quoted operands may contain spaces and semicolons; the quotes are removed.
+func splitEPDOps(s string) [][]string {
+	var ops [][]string
+	var op []string
+	var token strings.Builder
+	quoted, inToken := false, false
+
+	endToken := func() {
+		if inToken {
+			op = append(op, token.String())
+			token.Reset()
+			inToken = false
+		}
+	}
+
+	for _, c := range s {
+		switch {
+		case c == '"':
+			if quoted {
+				endToken()
+			} else {
+				inToken = true
+			}
+			quoted = !quoted
+		case quoted:
+			token.WriteRune(c)
+		case c == ';':
+			endToken()
+			ops = append(ops, op)
+			op = nil
+		case c == ' ' || c == '\t':
+			endToken()
+		default:
+			token.WriteRune(c)
+			inToken = true
+		}
+	}
+	endToken()
+	if len(op) != 0 {
+		ops = append(ops, op)
+	}
+
+	return ops
+}
@@ -0,0 +1,160 @@
+package uci
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// epdGo is the 'go' command for each test position. It has to be a time
+// control, otherwise Go passes the search straight through to Stockfish and
+// the troll move selection never runs.
+const epdGo = "go wtime 300000 btime 300000 winc 0 binc 0"
+
+// EPDResult is the move the proxy played in a test position.
+type EPDResult struct {
+	EPD  EPD
+	Move string
+}
+
+// Best returns true if the move is one of the position's 'bm' moves.
+func (r EPDResult) Best() bool {
+	return containsString(r.EPD.BestMoves, r.Move)
+}
+
+// Avoided returns true if the move is one of the position's 'am' moves, which
+// the troll may well want to play.
+func (r EPDResult) Avoided() bool {
+	return containsString(r.EPD.AvoidMoves, r.Move)
+}
+
+func (r EPDResult) String() string {
+	b := r.EPD.Board
+	san := func(moves []string) string {
+		v := make([]string, len(moves))
+		for i, move := range moves {
+			v[i] = b.sanOrUCI(move)
+		}
+		return strings.Join(v, " ")
+	}
+
+	s := fmt.Sprintf("%s move: %s", r.EPD.ID, b.sanOrUCI(r.Move))
+	if len(r.EPD.BestMoves) != 0 {
+		s += fmt.Sprintf(" bm: %s %v", san(r.EPD.BestMoves), r.Best())
+	}
+	if len(r.EPD.AvoidMoves) != 0 {
+		s += fmt.Sprintf(" am: %s %v", san(r.EPD.AvoidMoves), r.Avoided())
+	}
+	return s
+}
+
+// RunEPD starts Stockfish and plays each position through the proxy the same
+// way a GUI would, with 'ucinewgame', 'position' and a timed 'go', so the move
+// comes out of the same selection as in a game. report is called after each
+// position. Unlike Start, it doesn't log, touch stderr or use book learning.
+func (u *UCI) RunEPD(ctx context.Context, positions []EPD, report func(EPDResult)) ([]EPDResult, error) {
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+	u.in, u.out = inR, outW
+	u.log = nopWriteCloser{io.Discard}
+
+	// the test positions aren't games
+	u.recordGames = false
+
+	u.ctx, u.cancel = context.WithCancel(ctx)
+	sf, err := StartStockfish(u.ctx, u.logInfo)
+	if err != nil {
+		u.cancel()
+		return nil, err
+	}
+	u.startEngine(sf)
+	u.readCommands()
+
+	ctx = u.ctx
+	defer func() {
+		u.Quit()
+		_ = inW.Close()
+		_ = outW.Close()
+	}()
+
+	lines := make(chan string, 512)
+	go func() {
+		defer close(lines)
+		s := bufio.NewScanner(outR)
+		for s.Scan() {
+			select {
+			case lines <- s.Text():
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	send := func(cmd string) {
+		_, _ = io.WriteString(inW, cmd+"\n")
+	}
+
+	// wait returns the first line starting with prefix
+	wait := func(prefix string) (string, error) {
+		for {
+			select {
+			case line, ok := <-lines:
+				if !ok {
+					return "", fmt.Errorf("proxy exited waiting for '%s'", prefix)
+				}
+				if strings.HasPrefix(line, prefix) {
+					return line, nil
+				}
+			case <-ctx.Done():
+				return "", ctx.Err()
+			}
+		}
+	}
+
+	send("uci")
+	if _, err := wait("uciok"); err != nil {
+		return nil, err
+	}
+
+	results := make([]EPDResult, 0, len(positions))
+	for _, e := range positions {
+		send("ucinewgame")
+		send("position fen " + e.Board.FEN())
+		send("isready")
+		if _, err := wait("readyok"); err != nil {
+			return results, err
+		}
+
+		send(epdGo)
+		line, err := wait("bestmove ")
+		if err != nil {
+			return results, err
+		}
+
+		r := EPDResult{EPD: e, Move: strings.Fields(line)[1]}
+		results = append(results, r)
+		if report != nil {
+			report(r)
+		}
+	}
+
+	return results, nil
+}
+
+// nopWriteCloser is a log which is thrown away.
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
+
+func containsString(v []string, s string) bool {
+	for _, item := range v {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
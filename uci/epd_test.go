@@ -0,0 +1,121 @@
+package uci
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseEPD(t *testing.T) {
+	// arrange
+	cases := []struct {
+		line     string
+		fen      string
+		id       string
+		comment  string
+		bm       []string
+		am       []string
+		expError string
+	}{
+		{
+			line: `2rr3k/pp3pp1/1nnqbN1p/3pN3/2pP4/2P3Q1/PPB4P/R4RK1 w - - bm Qg6; id "WAC.001";`,
+			fen:  "2rr3k/pp3pp1/1nnqbN1p/3pN3/2pP4/2P3Q1/PPB4P/R4RK1 w - - 0 1",
+			id:   "WAC.001",
+			bm:   []string{"g3g6"},
+		},
+		{
+			line:    `rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - bm e4 d4 Nf3; am f3 g4; c0 "the usual; or not"; hmvc 3; fmvn 7;`,
+			fen:     "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 3 7",
+			comment: "the usual; or not",
+			bm:      []string{"e2e4", "d2d4", "g1f3"},
+			am:      []string{"f2f3", "g2g4"},
+		},
+		{
+			line: `4k3/8/8/8/8/8/8/4K2R w K - bm e1g1;`,
+			fen:  "4k3/8/8/8/8/8/8/4K2R w K - 0 1",
+			bm:   []string{"e1g1"},
+		},
+		{
+			line: `4k3/8/8/8/8/8/8/4K2R w K -`,
+			fen:  "4k3/8/8/8/8/8/8/4K2R w K - 0 1",
+		},
+		{
+			line:     `4k3/8/8/8/8/8/8/4K2R w K - bm Rh9;`,
+			expError: "opcode 'bm'",
+		},
+		{
+			line:     `4k3/8/8/8/8/8/8/4K2R w K - fmvn 0;`,
+			expError: "opcode 'fmvn' operand '0' is invalid",
+		},
+		{
+			line:     `4k3/8/8/8/8/8/8/4K2R w K`,
+			expError: "has 3 fields, want at least 4",
+		},
+		{
+			line:     `4k3/8/8/8/8/8/8/4K2R w Q - bm Kf1;`,
+			expError: "invalid FEN",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.line, func(t *testing.T) {
+			// act
+			e, err := ParseEPD(c.line)
+
+			// assert
+			if c.expError != "" {
+				if err == nil || !strings.Contains(err.Error(), c.expError) {
+					t.Fatalf("want error containing '%s', got: %v", c.expError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := e.Board.FEN(); got != c.fen {
+				t.Errorf("fen\nwant: '%s'\ngot:  '%s'", c.fen, got)
+			}
+			if e.ID != c.id {
+				t.Errorf("id, want: '%s' got: '%s'", c.id, e.ID)
+			}
+			if e.Comment != c.comment {
+				t.Errorf("c0, want: '%s' got: '%s'", c.comment, e.Comment)
+			}
+			if !reflect.DeepEqual(e.BestMoves, c.bm) {
+				t.Errorf("bm, want: %v got: %v", c.bm, e.BestMoves)
+			}
+			if !reflect.DeepEqual(e.AvoidMoves, c.am) {
+				t.Errorf("am, want: %v got: %v", c.am, e.AvoidMoves)
+			}
+		})
+	}
+}
+
+func TestReadEPD(t *testing.T) {
+	// arrange
+	file := `# a comment
+2rr3k/pp3pp1/1nnqbN1p/3pN3/2pP4/2P3Q1/PPB4P/R4RK1 w - - bm Qg6; id "WAC.001";
+
+8/7p/5k2/5p2/p1p2P2/Pr1pPK2/1P1R3P/8 b - - bm Rxb2; id "WAC.002";
+`
+
+	// act
+	positions, err := ReadEPD(strings.NewReader(file))
+
+	// assert
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(positions) != 2 {
+		t.Fatalf("want 2 positions, got %d", len(positions))
+	}
+	if positions[1].ID != "WAC.002" || !reflect.DeepEqual(positions[1].BestMoves, []string{"b3b2"}) {
+		t.Errorf("want WAC.002 bm b3b2, got %s bm %v", positions[1].ID, positions[1].BestMoves)
+	}
+
+	_, err = ReadEPD(strings.NewReader(file + "8/8 w - -\n"))
+	if err == nil || !strings.HasPrefix(err.Error(), "line 5: ") {
+		t.Errorf("want error on line 5, got: %v", err)
+	}
+}
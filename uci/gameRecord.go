@@ -0,0 +1,242 @@
+package uci
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// pgnDir is where a PGN file is written for each game.
+const pgnDir = "games"
+
+// gameRecord accumulates a game between 'ucinewgame' commands so it can be
+// saved as PGN, with a comment on each of our moves.
+type gameRecord struct {
+	started time.Time
+	start   Board
+	moves   []string
+
+	// ours holds our moves by ply, the index in moves
+	ours     map[int]recordedMove
+	ourColor Color
+}
+
+type recordedMove struct {
+	move    string
+	comment string
+
+	// book is set for moves from a book, which BookLearning learns from
+	book bool
+}
+
+// setPosition records the position the GUI sent; it repeats the whole game
+// each time, so it replaces the moves recorded so far. The caller must hold
+// moveListMtx.
+func (r *gameRecord) setPosition(start Board, moves []string) {
+	if r.started.IsZero() {
+		r.started = time.Now()
+	}
+	r.start = start
+	r.moves = append(r.moves[:0], moves...)
+}
+
+// addMove records the move we're about to play in the position b, the last
+// one set, with its comment. The caller must hold moveListMtx.
+func (r *gameRecord) addMove(b *Board, move, comment string) {
+	if r.ours == nil {
+		r.ours = make(map[int]recordedMove)
+	}
+	r.ours[len(r.moves)] = recordedMove{move: move, comment: comment}
+	r.ourColor = b.ActiveColor
+}
+
+// addBookMove records a book move we're about to play in the position b. The
+// caller must hold moveListMtx.
+func (r *gameRecord) addBookMove(b *Board, move string) {
+	r.addMove(b, move, "book")
+	ours := r.ours[len(r.moves)]
+	ours.book = true
+	r.ours[len(r.moves)] = ours
+}
+
+// learn adds the result of the game to the book moves we played in it.
+func (r *gameRecord) learn(l *BookLearning, result string) {
+	b := r.start
+	for i := 0; i <= len(r.moves); i++ {
+		ours, ok := r.ours[i]
+		if ok && ours.book && (i == len(r.moves) || r.moves[i] == ours.move) {
+			l.Add(&b, ours.move, result)
+		}
+		if i < len(r.moves) {
+			b.Moves(r.moves[i])
+		}
+	}
+}
+
+// Game returns the game recorded so far. Our last move is included if the GUI
+// didn't send a position after it, for example because it ended the game.
+func (r *gameRecord) Game(chess960 bool) *Game {
+	var g Game
+	g.start = r.start
+	g.start.Chess960 = chess960
+
+	b := g.StartBoard()
+	var history History
+	history = append(history, b.Key())
+	for i, move := range r.moves {
+		g.Moves = append(g.Moves, r.gameMove(&b, i, move))
+		b.Moves(move)
+		history = append(history, b.Key())
+	}
+
+	if last, ok := r.ours[len(r.moves)]; ok && b.IsLegalMove(last.move) {
+		g.Moves = append(g.Moves, r.gameMove(&b, len(r.moves), last.move))
+		b.Moves(last.move)
+		history = append(history, b.Key())
+	}
+
+	white, black := "?", "?"
+	if r.ourColor == White {
+		white = "trollfish"
+	} else {
+		black = "trollfish"
+	}
+
+	g.Result = "*"
+	switch b.Termination(history) {
+	case Checkmate:
+		g.Result = "1-0"
+		if b.ActiveColor == White {
+			g.Result = "0-1"
+		}
+	case Stalemate, InsufficientMaterial, FiftyMoveRule, ThreefoldRepetition:
+		g.Result = "1/2-1/2"
+	}
+
+	g.SetTag("Event", "trollfish game")
+	g.SetTag("Site", "?")
+	g.SetTag("Date", r.started.Format("2006.01.02"))
+	g.SetTag("Round", "-")
+	g.SetTag("White", white)
+	g.SetTag("Black", black)
+	g.SetTag("Result", g.Result)
+	if fen := g.start.FEN(); fen != startPosFEN || chess960 {
+		g.SetTag("SetUp", "1")
+		g.SetTag("FEN", fen)
+	}
+	if chess960 {
+		g.SetTag("Variant", "Chess960")
+	} else if o, ok := ClassifyOpening(history); ok {
+		g.SetTag("ECO", o.ECO)
+		g.SetTag("Opening", o.Name)
+	}
+
+	return &g
+}
+
+// gameMove returns the move at the ply, with our comment if it's the move we
+// chose.
+func (r *gameRecord) gameMove(b *Board, ply int, move string) GameMove {
+	m := GameMove{Move: move, SAN: b.sanOrUCI(move)}
+	if ours, ok := r.ours[ply]; ok && ours.move == move && ours.comment != "" {
+		m.Comments = []string{ours.comment}
+	}
+	return m
+}
+
+// moveComment returns the comment for our move: its eval in the '[%eval]'
+// format lichess reads, whether we're agro, and Stockfish's move if we didn't
+// play it. Evals are from White's point of view.
+func moveComment(b *Board, move, sfMove string, eval, sfEval Info, agro bool) string {
+	comment := pgnEval(b, eval)
+	if agro {
+		comment += " agro"
+	}
+	if sfMove != move {
+		comment += fmt.Sprintf(" sfbm %s (%s)", b.sanOrUCI(sfMove), formatEval(b, sfEval))
+	}
+	return comment
+}
+
+// pgnEval returns the score as a '[%eval]' command, e.g. "[%eval -0.35]" or
+// "[%eval #3]", from White's point of view.
+func pgnEval(b *Board, score Info) string {
+	return fmt.Sprintf("[%%eval %s]", formatEval(b, score))
+}
+
+// formatEval returns the score from White's point of view in pawns, or "#n"
+// for mate in n.
+func formatEval(b *Board, score Info) string {
+	sign := 1
+	if b.ActiveColor == Black {
+		sign = -1
+	}
+	if score.Mate != 0 {
+		return fmt.Sprintf("#%d", sign*score.Mate)
+	}
+	return fmt.Sprintf("%0.2f", float64(sign*score.Score)/100)
+}
+
+// learnGame adds the result of the game to the book learning and saves it,
+// unless learning is frozen. A game without a result is adjudicated from our
+// last eval, and isn't learned from if neither side was clearly winning.
+func (u *UCI) learnGame(r *gameRecord, result string) {
+	if u.freezeLearning {
+		return
+	}
+
+	if result == "*" {
+		u.moveListMtx.Lock()
+		result = adjudicate(r.ourColor, u.gameEval, u.gameMateIn)
+		u.moveListMtx.Unlock()
+	}
+	if result == "*" {
+		return
+	}
+
+	r.learn(u.learning, result)
+	if err := u.learning.Save(bookLearnFile); err != nil {
+		u.logInfo(fmt.Sprintf("ERR: book learning: %v", err))
+	}
+}
+
+// saveGame writes the recorded game to pgnDir and starts a new record. Games
+// without moves aren't saved, and nothing is saved if recordGames is off.
+func (u *UCI) saveGame() {
+	u.moveListMtx.Lock()
+	r := u.game
+	u.game = gameRecord{}
+	u.moveListMtx.Unlock()
+
+	if !u.recordGames || (len(r.moves) == 0 && len(r.ours) == 0) {
+		return
+	}
+
+	g := r.Game(u.chess960)
+	u.learnGame(&r, g.Result)
+
+	if err := os.MkdirAll(pgnDir, 0755); err != nil {
+		u.logInfo(fmt.Sprintf("ERR: save game: %v", err))
+		return
+	}
+
+	name := filepath.Join(pgnDir, r.started.Format("2006-01-02_150405.000")+".pgn")
+	fp, err := os.Create(name)
+	if err != nil {
+		u.logInfo(fmt.Sprintf("ERR: save game: %v", err))
+		return
+	}
+
+	if err := g.WritePGN(fp); err != nil {
+		_ = fp.Close()
+		u.logInfo(fmt.Sprintf("ERR: save game: %v", err))
+		return
+	}
+	if err := fp.Close(); err != nil {
+		u.logInfo(fmt.Sprintf("ERR: save game: %v", err))
+		return
+	}
+
+	u.logInfo(fmt.Sprintf("game saved: %s %s", name, g.Result))
+}
@@ -0,0 +1,121 @@
+package uci
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGameRecord(t *testing.T) {
+	// arrange
+	cases := []struct {
+		name    string
+		fen     string
+		moves   string
+		ours    map[int]recordedMove
+		want    string
+		result  string
+		opening string
+	}{
+		{
+			name:  "our last move ends the game",
+			fen:   startPosFEN,
+			moves: "e2e4 e7e5 d1h5 b8c6 f1c4 g8f6",
+			ours: map[int]recordedMove{
+				0: {move: "e2e4", comment: "book"},
+				2: {move: "d1h5", comment: "[%eval 0.10] sfbm Nf3 (0.35)"},
+				4: {move: "f1c4", comment: "[%eval 0.50]"},
+				6: {move: "h5f7", comment: "[%eval #1] agro"},
+			},
+			want:    "1. e4 { book } 1... e5 2. Qh5 { [%eval 0.10] sfbm Nf3 (0.35) } 2... Nc6 3. Bc4 { [%eval 0.50] } 3... Nf6 4. Qxf7# { [%eval #1] agro } 1-0",
+			result:  "1-0",
+			opening: "C20 King's Pawn Game: Wayward Queen Attack",
+		},
+		{
+			name:  "playing black from a FEN, unfinished",
+			fen:   "4k3/8/8/8/8/8/4P3/4K3 w - - 0 1",
+			moves: "e2e4",
+			ours: map[int]recordedMove{
+				1: {move: "e8d7", comment: "[%eval 0.00]"},
+			},
+			want:   "1. e4 Kd7 { [%eval 0.00] } *",
+			result: "*",
+		},
+		{
+			name:   "only our moves get comments",
+			fen:    "4k3/8/8/8/8/8/8/4K2R w K - 0 1",
+			moves:  "e1g1 e8d7",
+			ours:   map[int]recordedMove{0: {move: "e1f1", comment: "never played"}},
+			want:   "1. O-O Kd7 *",
+			result: "*",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			r := gameRecord{started: time.Date(2022, 2, 3, 0, 0, 0, 0, time.UTC)}
+			b := FENtoBoard(c.fen)
+			r.setPosition(b, strings.Fields(c.moves))
+			r.ours = c.ours
+
+			// act
+			g := r.Game(false)
+
+			// assert
+			var sb strings.Builder
+			if err := g.WritePGN(&sb); err != nil {
+				t.Fatal(err)
+			}
+			pgn := sb.String()
+			moveText := strings.TrimSpace(pgn[strings.Index(pgn, "\n\n"):])
+			moveText = strings.Join(strings.Fields(moveText), " ")
+			if moveText != c.want {
+				t.Errorf("\nwant: %s\ngot:  %s", c.want, moveText)
+			}
+			if g.Result != c.result || g.Tag("Result") != c.result {
+				t.Errorf("result, want: %s got: %s (tag %s)", c.result, g.Result, g.Tag("Result"))
+			}
+			if got := g.Tag("Date"); got != "2022.02.03" {
+				t.Errorf("date, want: 2022.02.03 got: %s", got)
+			}
+			if got := strings.TrimSpace(g.Tag("ECO") + " " + g.Tag("Opening")); got != c.opening {
+				t.Errorf("opening, want: '%s' got: '%s'", c.opening, got)
+			}
+			if c.fen != startPosFEN && g.Tag("FEN") != c.fen {
+				t.Errorf("FEN tag, want: %s got: %s", c.fen, g.Tag("FEN"))
+			}
+		})
+	}
+}
+
+func TestMoveComment(t *testing.T) {
+	// arrange
+	cases := []struct {
+		name   string
+		fen    string
+		move   string
+		sfMove string
+		eval   Info
+		sfEval Info
+		agro   bool
+		want   string
+	}{
+		{name: "same move", fen: startPosFEN, move: "e2e4", sfMove: "e2e4", eval: Info{Score: 35}, sfEval: Info{Score: 35}, want: "[%eval 0.35]"},
+		{name: "troll move as black", fen: "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq - 0 1", move: "g7g5", sfMove: "e7e5", eval: Info{Score: -150}, sfEval: Info{Score: -30}, want: "[%eval 1.50] sfbm e5 (0.30)"},
+		{name: "agro mate", fen: "4k3/R7/1R6/8/8/8/8/4K3 w - - 0 1", move: "b6b8", sfMove: "b6b8", eval: Info{Mate: 1}, sfEval: Info{Mate: 1}, agro: true, want: "[%eval #1] agro"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			b := FENtoBoard(c.fen)
+
+			// act
+			got := moveComment(&b, c.move, c.sfMove, c.eval, c.sfEval, c.agro)
+
+			// assert
+			if got != c.want {
+				t.Errorf("want: '%s' got: '%s'", c.want, got)
+			}
+		})
+	}
+}
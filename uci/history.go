@@ -0,0 +1,31 @@
+package uci
+
+// History is the list of position keys in a game, from the position given to
+// the 'position' command up to and including the current position.
+type History []uint64
+
+// Repetitions returns how many times the position key occurs in the history.
+func (h History) Repetitions(key uint64) int {
+	var n int
+	for _, k := range h {
+		if k == key {
+			n++
+		}
+	}
+	return n
+}
+
+// RepetitionsAfter returns how many times the position after playing the move
+// on b would have occurred, including the new occurrence. b must be the last
+// position in the history.
+func (h History) RepetitionsAfter(b *Board, move string) int {
+	c := b.clone()
+	c.Moves(move)
+	return h.Repetitions(c.Key()) + 1
+}
+
+// PliesToFiftyMoveRule returns the number of plies left until a draw can be
+// claimed under the fifty-move rule, 0 if it can be claimed now.
+func (b *Board) PliesToFiftyMoveRule() int {
+	return max(100-b.HalfmoveClock, 0)
+}
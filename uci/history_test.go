@@ -0,0 +1,63 @@
+package uci
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRepetitions(t *testing.T) {
+	// arrange
+	b := FENtoBoard(startPosFEN)
+	history := History{b.Key()}
+	for _, move := range strings.Split("g1f3 g8f6 f3g1 f6g8 g1f3 g8f6 f3g1", " ") {
+		b.Moves(move)
+		history = append(history, b.Key())
+	}
+
+	// act
+	start := FENtoBoard(startPosFEN)
+	gotStart := history.Repetitions(start.Key())
+	gotCurrent := history.Repetitions(b.Key())
+	gotAfterRepeat := history.RepetitionsAfter(&b, "f6g8")
+	gotAfterOther := history.RepetitionsAfter(&b, "e7e5")
+
+	// assert
+	if gotStart != 2 {
+		t.Errorf("start position, want: %d got: %d", 2, gotStart)
+	}
+	if gotCurrent != 2 {
+		t.Errorf("current position, want: %d got: %d", 2, gotCurrent)
+	}
+	if gotAfterRepeat != 3 {
+		t.Errorf("after f6g8, want: %d got: %d", 3, gotAfterRepeat)
+	}
+	if gotAfterOther != 1 {
+		t.Errorf("after e7e5, want: %d got: %d", 1, gotAfterOther)
+	}
+}
+
+func TestPliesToFiftyMoveRule(t *testing.T) {
+	// arrange
+	cases := []struct {
+		fen  string
+		want int
+	}{
+		{fen: startPosFEN, want: 100},
+		{fen: "8/8/3k1p2/5P2/3K4/8/8/8 b - - 39 135", want: 61},
+		{fen: "8/8/3k1p2/5P2/3K4/8/8/8 b - - 100 135", want: 0},
+		{fen: "8/8/3k1p2/5P2/3K4/8/8/8 b - - 120 135", want: 0},
+	}
+
+	for _, c := range cases {
+		t.Run(c.fen, func(t *testing.T) {
+			// act
+			b := FENtoBoard(c.fen)
+			got := b.PliesToFiftyMoveRule()
+
+			// assert
+			if got != c.want {
+				t.Errorf("want: %d got: %d", c.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package uci
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Move is a move in UCI terms: the from and to squares and the piece type to
+// promote to. Castling is the king's move; whether it's written as e1g1 or as
+// the king taking its own rook (e1h1) depends on Board.Chess960.
+type Move uint16
+
+// NoMove is the zero Move, a1a1, which is never legal.
+const NoMove Move = 0
+
+func NewMove(from, to Square, promotion PieceType) Move {
+	return Move(from) | Move(to)<<6 | Move(promotion)<<12
+}
+
+// ParseMove reads a move in UCI notation, e.g. "e2e4" or "e7e8q".
+func ParseMove(s string) (Move, error) {
+	if len(s) != 4 && len(s) != 5 {
+		return NoMove, fmt.Errorf("move '%s' is malformed", s)
+	}
+
+	from, err := ParseSquare(s[:2])
+	if err != nil {
+		return NoMove, fmt.Errorf("move '%s' is malformed", s)
+	}
+	to, err := ParseSquare(s[2:4])
+	if err != nil {
+		return NoMove, fmt.Errorf("move '%s' is malformed", s)
+	}
+
+	promotion := NoPieceType
+	if len(s) == 5 {
+		i := strings.IndexByte("nbrq", s[4])
+		if i == -1 {
+			return NoMove, fmt.Errorf("move '%s' has unknown promotion '%c'", s, s[4])
+		}
+		promotion = Knight + PieceType(i)
+	}
+
+	return NewMove(from, to, promotion), nil
+}
+
+func (m Move) From() Square {
+	return Square(m & 63)
+}
+
+func (m Move) To() Square {
+	return Square(m >> 6 & 63)
+}
+
+func (m Move) Promotion() PieceType {
+	return PieceType(m >> 12 & 7)
+}
+
+// String returns the move in UCI notation.
+func (m Move) String() string {
+	s := m.From().String() + m.To().String()
+	if p := m.Promotion(); p != NoPieceType {
+		s += p.String()
+	}
+	return s
+}
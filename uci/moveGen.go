@@ -0,0 +1,230 @@
+package uci
+
+var (
+	knightOffsets = [8][2]int{{1, 2}, {2, 1}, {2, -1}, {1, -2}, {-1, -2}, {-2, -1}, {-2, 1}, {-1, 2}}
+	kingOffsets   = [8][2]int{{0, 1}, {1, 1}, {1, 0}, {1, -1}, {0, -1}, {-1, -1}, {-1, 0}, {-1, 1}}
+	promotions    = [4]PieceType{Queen, Rook, Bishop, Knight}
+)
+
+// LegalMoves returns all legal moves for the side to move in UCI notation.
+func (b *Board) LegalMoves() []string {
+	moves := b.GenerateMoves()
+	legal := make([]string, len(moves))
+	for i, m := range moves {
+		legal[i] = m.String()
+	}
+	return legal
+}
+
+// GenerateMoves returns all legal moves for the side to move.
+func (b *Board) GenerateMoves() []Move {
+	us := b.ActiveColor
+
+	moves := b.pseudoLegalMoves(make([]Move, 0, 64))
+	legal := moves[:0]
+	for _, m := range moves {
+		c := *b
+		c.MakeMove(m)
+		if king := c.kingSquare(us); king != NoSquare && c.isAttacked(king, us.Other()) {
+			continue
+		}
+		legal = append(legal, m)
+	}
+
+	return legal
+}
+
+// IsLegalMove returns true if the UCI move is legal for the side to move.
+func (b *Board) IsLegalMove(move string) bool {
+	m, err := ParseMove(move)
+	if err != nil {
+		return false
+	}
+	return containsMove(b.GenerateMoves(), m)
+}
+
+// InCheck returns true if the side to move is in check.
+func (b *Board) InCheck() bool {
+	return b.Checkers() != 0
+}
+
+// Checkers returns the squares of the pieces giving check to the side to move.
+func (b *Board) Checkers() Bitboard {
+	king := b.kingSquare(b.ActiveColor)
+	if king == NoSquare {
+		return 0
+	}
+	return b.attackersTo(king, b.occupied()) & b.colors[b.ActiveColor.Other()]
+}
+
+func (b *Board) pseudoLegalMoves(moves []Move) []Move {
+	us := b.ActiveColor
+	own, occupied := b.colors[us], b.occupied()
+
+	add := func(from Square, targets Bitboard) {
+		for ; targets != 0; targets &= targets - 1 {
+			moves = append(moves, NewMove(from, targets.first(), NoPieceType))
+		}
+	}
+
+	for pieces := own; pieces != 0; pieces &= pieces - 1 {
+		from := pieces.first()
+
+		switch b.squares[from].Type() {
+		case Pawn:
+			moves = b.appendPawnMoves(moves, from)
+		case Knight:
+			add(from, knightAttacks[from]&^own)
+		case Bishop:
+			add(from, bishopAttacks(from, occupied)&^own)
+		case Rook:
+			add(from, rookAttacks(from, occupied)&^own)
+		case Queen:
+			add(from, (bishopAttacks(from, occupied)|rookAttacks(from, occupied))&^own)
+		case King:
+			add(from, kingAttacks[from]&^own)
+			moves = b.appendCastling(moves, from)
+		}
+	}
+
+	return moves
+}
+
+func (b *Board) appendPawnMoves(moves []Move, from Square) []Move {
+	us := b.ActiveColor
+
+	dir, startRank, promoteRank := Square(8), 1, 7
+	if us == Black {
+		dir, startRank, promoteRank = -8, 6, 0
+	}
+
+	add := func(to Square) {
+		if to.Rank() != promoteRank {
+			moves = append(moves, NewMove(from, to, NoPieceType))
+			return
+		}
+		for _, p := range promotions {
+			moves = append(moves, NewMove(from, to, p))
+		}
+	}
+
+	// pushes
+	if to := from + dir; b.squares[to] == NoPiece {
+		add(to)
+		if from.Rank() == startRank && b.squares[to+dir] == NoPiece {
+			add(to + dir)
+		}
+	}
+
+	// captures, including en passant
+	targets := b.colors[us.Other()]
+	if b.EnPassantSquare != NoSquare {
+		targets |= b.EnPassantSquare.bitboard()
+	}
+	for attacks := pawnAttacks[us][from] & targets; attacks != 0; attacks &= attacks - 1 {
+		add(attacks.first())
+	}
+
+	return moves
+}
+
+// appendCastling adds castling moves for the king on from. In Chess960 the
+// king and rook can start anywhere on the back rank, so the squares both
+// pieces cross must be empty apart from the king and the castling rook.
+func (b *Board) appendCastling(moves []Move, from Square) []Move {
+	us := b.ActiveColor
+	color, rook, rank := castleWhiteKing, WhiteRook, 0
+	if us == Black {
+		color, rook, rank = castleBlackKing, BlackRook, 7
+	}
+
+	kingFile := from.File()
+	if from.Rank() != rank || (!b.Chess960 && kingFile != 4) {
+		return moves
+	}
+
+	for side := 0; side < 2; side++ {
+		rookFile := b.castling[color+side]
+		if rookFile == -1 || b.squares[makeSquare(rookFile, rank)] != rook {
+			continue
+		}
+
+		kingTo, rookTo := castlingTargets(color + side)
+
+		canCastle := true
+		for _, span := range [2][2]int{{kingFile, kingTo}, {rookFile, rookTo}} {
+			for f := min(span[0], span[1]); f <= max(span[0], span[1]); f++ {
+				if f != kingFile && f != rookFile && b.squares[makeSquare(f, rank)] != NoPiece {
+					canCastle = false
+				}
+			}
+		}
+		// the destination square is checked by the legality filter
+		for f := min(kingFile, kingTo); f <= max(kingFile, kingTo) && canCastle; f++ {
+			if b.isAttacked(makeSquare(f, rank), us.Other()) {
+				canCastle = false
+			}
+		}
+		if !canCastle {
+			continue
+		}
+
+		to := makeSquare(kingTo, rank)
+		if b.Chess960 {
+			to = makeSquare(rookFile, rank)
+		}
+		moves = append(moves, NewMove(from, to, NoPieceType))
+	}
+
+	return moves
+}
+
+// attackersTo returns the pieces of either color attacking sq, with sliders
+// blocked by the given occupancy.
+func (b *Board) attackersTo(sq Square, occupied Bitboard) Bitboard {
+	queens := b.pieces[WhiteQueen] | b.pieces[BlackQueen]
+	rooks := b.pieces[WhiteRook] | b.pieces[BlackRook] | queens
+	bishops := b.pieces[WhiteBishop] | b.pieces[BlackBishop] | queens
+
+	// a white pawn attacks sq from the squares a black pawn on sq would attack
+	return pawnAttacks[Black][sq]&b.pieces[WhitePawn] |
+		pawnAttacks[White][sq]&b.pieces[BlackPawn] |
+		knightAttacks[sq]&(b.pieces[WhiteKnight]|b.pieces[BlackKnight]) |
+		kingAttacks[sq]&(b.pieces[WhiteKing]|b.pieces[BlackKing]) |
+		rookAttacks(sq, occupied)&rooks |
+		bishopAttacks(sq, occupied)&bishops
+}
+
+// isAttacked returns true if sq is attacked by any piece of the given color.
+func (b *Board) isAttacked(sq Square, by Color) bool {
+	if pawnAttacks[by.Other()][sq]&b.pieces[makePiece(by, Pawn)] != 0 ||
+		knightAttacks[sq]&b.pieces[makePiece(by, Knight)] != 0 ||
+		kingAttacks[sq]&b.pieces[makePiece(by, King)] != 0 {
+		return true
+	}
+
+	occupied := b.occupied()
+	queens := b.pieces[makePiece(by, Queen)]
+	return rookAttacks(sq, occupied)&(b.pieces[makePiece(by, Rook)]|queens) != 0 ||
+		bishopAttacks(sq, occupied)&(b.pieces[makePiece(by, Bishop)]|queens) != 0
+}
+
+// kingSquare returns the square of the color's king, or NoSquare.
+func (b *Board) kingSquare(c Color) Square {
+	return b.pieces[makePiece(c, King)].first()
+}
+
+// clone returns a copy of the board. Board holds no references, so this is
+// the same as copying the value.
+func (b *Board) clone() Board {
+	return *b
+}
+
+func containsMove(moves []Move, m Move) bool {
+	for _, move := range moves {
+		if move == m {
+			return true
+		}
+	}
+	return false
+}
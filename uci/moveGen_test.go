@@ -0,0 +1,243 @@
+package uci
+
+import (
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestLegalMoves(t *testing.T) {
+	// arrange
+	cases := []struct {
+		name     string
+		fen      string
+		wantLen  int
+		contains []string
+		excludes []string
+	}{
+		{
+			name:     "start position",
+			fen:      startPosFEN,
+			wantLen:  20,
+			contains: []string{"e2e4", "g1f3", "b1a3"},
+			excludes: []string{"e1e2", "e2e5"},
+		},
+		{
+			name:     "castle both sides",
+			fen:      "r3k2r/pppppppp/8/8/8/8/PPPPPPPP/R3K2R w KQkq - 0 1",
+			wantLen:  25,
+			contains: []string{"e1g1", "e1c1"},
+		},
+		{
+			name:     "no castling through check",
+			fen:      "r3k2r/8/8/8/8/8/5r2/R3K2R w KQkq - 0 1",
+			contains: []string{"e1c1"},
+			excludes: []string{"e1g1"},
+		},
+		{
+			name:     "no castling into check",
+			fen:      "r3k2r/8/8/8/8/8/6r1/R3K2R w KQkq - 0 1",
+			contains: []string{"e1c1"},
+			excludes: []string{"e1g1"},
+		},
+		{
+			name:     "no castling out of check",
+			fen:      "r3k2r/8/8/8/8/8/4r3/R3K2R w KQkq - 0 1",
+			excludes: []string{"e1g1", "e1c1"},
+		},
+		{
+			name:     "queen side castle with attacked b1",
+			fen:      "r3k2r/8/8/8/8/8/1r6/R3K2R w Qkq - 0 1",
+			contains: []string{"e1c1"},
+			excludes: []string{"e1g1"},
+		},
+		{
+			name:     "en passant",
+			fen:      "rnbqkbnr/ppp1p1pp/8/3pPp2/8/8/PPPP1PPP/RNBQKBNR w KQkq f6 0 3",
+			contains: []string{"e5f6"},
+			excludes: []string{"e5d6"},
+		},
+		{
+			name:     "en passant exposing king",
+			fen:      "8/8/8/K2pP2r/8/8/8/7k w - d6 0 1",
+			excludes: []string{"e5d6"},
+		},
+		{
+			name:     "promotions",
+			fen:      "3r3k/4P3/8/8/8/8/8/K7 w - - 0 1",
+			wantLen:  11,
+			contains: []string{"e7e8q", "e7e8n", "e7d8r", "e7d8b"},
+		},
+		{
+			name:     "pinned knight",
+			fen:      "4k3/4r3/8/8/8/8/4N3/4K3 w - - 0 1",
+			wantLen:  4,
+			excludes: []string{"e2c3", "e2g3"},
+		},
+		{
+			name:     "double check, king moves only",
+			fen:      "4k3/8/8/8/1b6/8/3P4/r3K1N1 w - - 0 1",
+			wantLen:  2,
+			contains: []string{"e1e2", "e1f2"},
+		},
+		{
+			name:    "checkmate",
+			fen:     "rnb1kbnr/pppp1ppp/8/4p3/6Pq/5P2/PPPPP2P/RNBQKBNR w KQkq - 1 3",
+			wantLen: 0,
+		},
+		{
+			name:    "stalemate",
+			fen:     "7k/5Q2/6K1/8/8/8/8/8 b - - 0 1",
+			wantLen: 0,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			// act
+			b := FENtoBoard(c.fen)
+			got := b.LegalMoves()
+
+			// assert
+			sort.Strings(got)
+			if c.wantLen != 0 || len(c.contains) == 0 && len(c.excludes) == 0 {
+				if len(got) != c.wantLen {
+					t.Errorf("len, want: %d got: %d (%s)", c.wantLen, len(got), strings.Join(got, " "))
+				}
+			}
+			for _, move := range c.contains {
+				if !b.IsLegalMove(move) {
+					t.Errorf("want %s to be legal (%s)", move, strings.Join(got, " "))
+				}
+			}
+			for _, move := range c.excludes {
+				if b.IsLegalMove(move) {
+					t.Errorf("want %s to be illegal (%s)", move, strings.Join(got, " "))
+				}
+			}
+		})
+	}
+}
+
+func TestInCheck(t *testing.T) {
+	// arrange
+	cases := []struct {
+		fen  string
+		want bool
+	}{
+		{fen: startPosFEN, want: false},
+		{fen: "rnb1kbnr/pppp1ppp/8/4p3/6Pq/5P2/PPPPP2P/RNBQKBNR w KQkq - 1 3", want: true},
+		{fen: "4k3/8/8/8/8/8/3p4/4K3 w - - 0 1", want: true},
+		{fen: "4k3/8/8/8/8/8/4p3/4K3 w - - 0 1", want: false},
+		{fen: "4k3/8/3N4/8/8/8/8/4K3 b - - 0 1", want: true},
+		{fen: "4k3/8/8/8/8/8/8/r3K3 w - - 0 1", want: true},
+		{fen: "4k3/8/8/8/8/8/8/r1B1K3 w - - 0 1", want: false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.fen, func(t *testing.T) {
+			// act
+			b := FENtoBoard(c.fen)
+			got := b.InCheck()
+
+			// assert
+			if got != c.want {
+				t.Errorf("want: %v got: %v", c.want, got)
+			}
+		})
+	}
+}
+
+func TestChess960Castling(t *testing.T) {
+	// arrange
+	cases := []struct {
+		fen     string
+		move    string
+		san     string
+		wantFEN string
+	}{
+		{fen: "4k3/8/8/8/8/8/8/1R2K1R1 w GB - 0 1", move: "e1g1", san: "O-O", wantFEN: "4k3/8/8/8/8/8/8/1R3RK1 b - - 1 1"},
+		{fen: "4k3/8/8/8/8/8/8/1R2K1R1 w GB - 0 1", move: "e1b1", san: "O-O-O", wantFEN: "4k3/8/8/8/8/8/8/2KR2R1 b - - 1 1"},
+		{fen: "rk6/8/8/8/8/8/8/RK6 w Qq - 0 1", move: "b1a1", san: "O-O-O", wantFEN: "rk6/8/8/8/8/8/8/2KR4 b q - 1 1"},
+		{fen: "6kr/8/8/8/8/8/8/6KR b Kk - 0 1", move: "g8h8", san: "O-O", wantFEN: "5rk1/8/8/8/8/8/8/6KR w K - 1 2"},
+		{fen: "r1k1r3/pppppppp/8/8/8/8/PPPPPPPP/R1K1R3 w EAea - 0 1", move: "c1e1", san: "O-O", wantFEN: "r1k1r3/pppppppp/8/8/8/8/PPPPPPPP/R4RK1 b kq - 1 1"},
+		{fen: "1r2k1rr/8/8/8/8/8/8/4K3 b gb - 0 1", move: "b8b7", san: "Rb7", wantFEN: "4k1rr/1r6/8/8/8/8/8/4K3 w g - 1 2"},
+		{fen: "r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1", move: "e1h1", san: "O-O", wantFEN: "r3k2r/8/8/8/8/8/8/R4RK1 b kq - 1 1"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.fen+" "+c.move, func(t *testing.T) {
+			// act
+			b := FENtoBoard(c.fen)
+			b.Chess960 = true
+			legal := b.IsLegalMove(c.move)
+			san, sanErr := b.SAN(c.move)
+			parsed, parseErr := b.ParseSAN(c.san)
+			b.Moves(c.move)
+
+			// assert
+			if !legal {
+				t.Errorf("want %s to be legal (%v)", c.move, b.LegalMoves())
+			}
+			if sanErr != nil || san != c.san {
+				t.Errorf("SAN, want: '%s' got: '%s' (%v)", c.san, san, sanErr)
+			}
+			if parseErr != nil || parsed != c.move {
+				t.Errorf("ParseSAN, want: '%s' got: '%s' (%v)", c.move, parsed, parseErr)
+			}
+			if got := b.FEN(); got != c.wantFEN {
+				t.Errorf("\nwant: '%s'\ngot:  '%s'", c.wantFEN, got)
+			}
+			if want := FENtoBoard(b.FEN()); b.Key() != want.Key() {
+				t.Errorf("key, want: %016x got: %016x", want.Key(), b.Key())
+			}
+		})
+	}
+}
+
+func TestChess960CastlingBlocked(t *testing.T) {
+	// arrange
+	cases := []struct {
+		fen      string
+		chess960 bool
+		excludes []string
+	}{
+		// the rook's destination d1 is occupied
+		{fen: "4k3/8/8/8/8/8/8/R2NK3 w Q - 0 1", chess960: true, excludes: []string{"e1a1"}},
+		// the king would pass through check on f1
+		{fen: "4kr2/8/8/8/8/8/8/2K4R w K - 0 1", chess960: true, excludes: []string{"c1h1"}},
+		// the king would land on g1, attacked by the rook on g8
+		{fen: "4k1r1/8/8/8/8/8/8/5K1R w K - 0 1", chess960: true, excludes: []string{"f1h1"}},
+		// castling can't be written without Chess960 notation when the king isn't on e1
+		{fen: "6kr/8/8/8/8/8/8/6KR w K - 0 1", chess960: false, excludes: []string{"g1g1", "g1h1"}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.fen, func(t *testing.T) {
+			// act
+			b := FENtoBoard(c.fen)
+			b.Chess960 = c.chess960
+
+			// assert
+			for _, move := range c.excludes {
+				if b.IsLegalMove(move) {
+					t.Errorf("want %s to be illegal (%v)", move, b.LegalMoves())
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkLegalMoves(b *testing.B) {
+	board := FENtoBoard("r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1")
+	for i := 0; i < b.N; i++ {
+		board.LegalMoves()
+	}
+}
+
+func BenchmarkInCheck(b *testing.B) {
+	board := FENtoBoard("r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1")
+	for i := 0; i < b.N; i++ {
+		board.InCheck()
+	}
+}
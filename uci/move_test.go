@@ -0,0 +1,46 @@
+package uci
+
+import "testing"
+
+func TestParseMove(t *testing.T) {
+	// arrange
+	cases := []struct {
+		move          string
+		wantFrom      Square
+		wantTo        Square
+		wantPromotion PieceType
+		wantErr       string
+	}{
+		{move: "e2e4", wantFrom: makeSquare(4, 1), wantTo: makeSquare(4, 3)},
+		{move: "a1h8", wantFrom: 0, wantTo: 63},
+		{move: "e7e8q", wantFrom: makeSquare(4, 6), wantTo: makeSquare(4, 7), wantPromotion: Queen},
+		{move: "b2a1n", wantFrom: makeSquare(1, 1), wantTo: 0, wantPromotion: Knight},
+		{move: "e2", wantErr: "move 'e2' is malformed"},
+		{move: "e2i4", wantErr: "move 'e2i4' is malformed"},
+		{move: "e7e8k", wantErr: "move 'e7e8k' has unknown promotion 'k'"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.move, func(t *testing.T) {
+			// act
+			m, err := ParseMove(c.move)
+
+			// assert
+			if c.wantErr != "" {
+				if err == nil || err.Error() != c.wantErr {
+					t.Errorf("want err: %s got err: %v", c.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if m.From() != c.wantFrom || m.To() != c.wantTo || m.Promotion() != c.wantPromotion {
+				t.Errorf("want: %s %s %d got: %s %s %d", c.wantFrom, c.wantTo, c.wantPromotion, m.From(), m.To(), m.Promotion())
+			}
+			if m.String() != c.move {
+				t.Errorf("String, want: '%s' got: '%s'", c.move, m.String())
+			}
+		})
+	}
+}
@@ -1,8 +1,7 @@
 package uci
 
 import (
-	"math/rand"
-	"strings"
+	"fmt"
 )
 
 type firstMove struct {
@@ -52,26 +51,58 @@ var firstMoveMap = []*firstMove{
 	{uci: "g2g4", freq: 0}, // 1. g4   -1.37
 }
 
-var firstMoveChoices []string
+// firstReplyMap holds Black's replies to White's first moves, weighted like
+// firstMoveMap: 30 for the best reply, less by how many centipawns a reply is
+// worse. It's the output of 'trollfish calibrate-first-moves'; a first move
+// without replies gets Stockfish's reply.
+var firstReplyMap = map[string][]*firstMove{}
+
+var firstMoves []BookMove
+
+// firstReplies is firstReplyMap by the key of the position after each first
+// move.
+var firstReplies map[uint64][]BookMove
 
 func init() {
 	for _, item := range firstMoveMap {
-		if item.freq == 0 {
-			continue
-		}
+		firstMoves = append(firstMoves, BookMove{Move: item.uci, Weight: item.freq})
+	}
+
+	firstReplies = makeFirstReplies(firstReplyMap)
+}
 
-		for i := 0; i < item.freq; i++ {
-			firstMoveChoices = append(firstMoveChoices, item.uci)
+// makeFirstReplies returns the replies to each first move by the key of the
+// position after it.
+func makeFirstReplies(replyMap map[string][]*firstMove) map[uint64][]BookMove {
+	replies := make(map[uint64][]BookMove)
+	for first, items := range replyMap {
+		b := FENtoBoard(startPosFEN)
+		b.Moves(first)
+		for _, item := range items {
+			replies[b.Key()] = append(replies[b.Key()], BookMove{Move: item.uci, Weight: item.freq})
 		}
 	}
+	return replies
+}
+
+// getFirstMove returns a first move from firstMoveMap, its weights adjusted by
+// book learning. l may be nil.
+func getFirstMove(l *BookLearning) string {
+	b := FENtoBoard(startPosFEN)
+	return pickWeighted(l.Apply(&b, firstMoves))
 }
 
-func getFirstMove() string {
-	n := rand.Intn(len(firstMoveChoices))
-	return firstMoveChoices[n]
+// getFirstReply returns Black's reply from firstReplyMap if b is the position
+// after White's first move, adjusted by book learning, or "". l may be nil.
+func getFirstReply(b *Board, l *BookLearning) string {
+	return pickWeighted(l.Apply(b, firstReplies[b.Key()]))
 }
 
 func (u *UCI) BookMove() string {
+	if move := u.PolyglotBookMove(); move != "" {
+		return move
+	}
+
 	if !u.gameAgro {
 		move := u.CasualBookMove()
 		if move != "" {
@@ -79,208 +110,149 @@ func (u *UCI) BookMove() string {
 		}
 	}
 
-	if u.fen == startPosFEN {
-		return getFirstMove()
-	}
-
-	return ""
-}
-
-func (u *UCI) CasualBookMove() string {
-	// Wayward Queen
-	if strings.HasPrefix(u.fen, "rnbqkbnr/pppp1ppp/8/4p3/4P3/8/PPPP1PPP/RNBQKBNR w") {
-		// 1. e4 e5 2. Qh5 (White, Wayward Queen)
-		return "d1h5"
-	}
-
-	// Englund Gambit
-	if strings.HasPrefix(u.fen, "rnbqkbnr/pppppppp/8/8/3P4/8/PPP1PPPP/RNBQKBNR b") {
-		// 1. d4 e5 (Black, Englund Gambit)
-		return "e7e5"
-	}
-
-	if strings.HasPrefix(u.fen, "rnbqkbnr/pppp1ppp/8/4p3/3P4/8/PPP1PPPP/RNBQKBNR w") {
-		// 1. d4 e5 2. dxe5 (White, Englund Gambit)
-		return "d4e5"
+	if u.atStartPos() {
+		return getFirstMove(u.learning)
 	}
 
-	if strings.HasPrefix(u.fen, "rnbqkbnr/pppp1ppp/8/4P3/8/8/PPP1PPPP/RNBQKBNR b") {
-		// 1. d4 e5 2. dxe5 Nc6 (Black, Englund Gambit)
-		return "b8c6"
-	}
-
-	if strings.HasPrefix(u.fen, "r1bqkbnr/pppp1ppp/2n5/4P3/8/8/PPP1PPPP/RNBQKBNR w") {
-		// 1. d4 e5 2. dxe5 Nc6 3. Nf3 (White, Englund Gambit)
-		return "g1f3"
-	}
-
-	if strings.HasPrefix(u.fen, "r1bqkbnr/pppp1ppp/2n5/4P3/8/5N2/PPP1PPPP/RNBQKB1R b") { // 3. Nf3
-		// 1. d4 e5 2. dxe5 Nc6 3. Nf3 Qe7 (Black, Englund Gambit)
-		return "d8e7"
-	}
-
-	if strings.HasPrefix(u.fen, "r1bqkbnr/pppp1ppp/2n5/4P3/5B2/8/PPP1PPPP/RN1QKBNR b") { // 3. Bf4
-		// 1. d4 e5 2. dxe5 Nc6 3. Bf4 Qe7 (Black, Englund Gambit)
-		return "d8e7"
-	}
-
-	if strings.HasPrefix(u.fen, "r1b1kbnr/ppppqppp/2n5/4P3/8/5N2/PPP1PPPP/RNBQKB1R w") { // 4. Bg5
-		// 1. d4 e5 2. dxe5 Nc6 3. Nf3 Qe7 4. Bg5 (White, Englund Gambit)
-		return "c1g5"
-	}
-
-	if strings.HasPrefix(u.fen, "r1b1kbnr/ppppqppp/2n5/4P1B1/8/5N2/PPP1PPPP/RN1QKB1R b") { // 4. Bg5 Qb4+
-		// 1. d4 e5 2. dxe5 Nc6 3. Nf3 Qe7 4. Bg5 Qb4+ (Black, Englund Gambit)
-		return "e7b4"
-	}
-
-	if strings.HasPrefix(u.fen, "r1b1kbnr/ppppqppp/2n5/4P3/5B2/5N2/PPP1PPPP/RN1QKB1R b") { // (Nf3, Bf4) ... Qb4+
-		// 1. d4 e5 2. dxe5 Nc6 3. Nf3 Qe7 4. Bg4 Qb4+ (Black, Englund Gambit)
-		return "e7b4"
-	}
-
-	if strings.HasPrefix(u.fen, "r1b1kbnr/pppp1ppp/2n5/4P1B1/1q6/2N2N2/PPP1PPPP/R2QKB1R b") { // Bg5 Nc3
-		// 1. d4 e5 2. dxe5 Nc6 3. Nf3 Qe7 4. Bg4 Qb4+ 5. Nc3 Qxc2 (Black, Englund Gambit)
-		return "b4b2"
-	}
-
-	if strings.HasPrefix(u.fen, "r1b1kbnr/pppp1ppp/2n5/4P3/8/2N2N2/PqPBPPPP/R2QKB1R b") { // Bc2 Bb4
-		return "f8b4"
-	}
-
-	// TODO: play against humans
-	/*if strings.HasPrefix(u.fen, "r1b1k1nr/pppp1ppp/2n5/4P3/1b6/2N2N2/PqPBPPPP/1R1QKB1R b") { // Bc2 Bb4 Rb1 ... sac!
-		return "b2c3"
-	}*/
-
-	if strings.HasPrefix(u.fen, "r1b1kbnr/pppp1ppp/2n5/4P3/1q6/5N2/PPPBPPPP/RN1QKB1R b") {
-		// 1. d4 e5 2. dxe5 Nc6 3. Nf3 Qe7 4. (Bg4, Bg5) Qb4+ 5. Bd2 Qxc2 (Black, Englund Gambit)
-		return "b4b2"
-	}
-
-	// Smith-Morra Gambit
-	if strings.HasPrefix(u.fen, "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b") {
-		// 1. e4 c5 (Black, Smith-Morra Gambit)
-		return "c7c5"
-	}
-
-	if strings.HasPrefix(u.fen, "rnbqkbnr/pp1ppppp/8/2p5/4P3/8/PPPP1PPP/RNBQKBNR w") {
-		// 1. e4 c5 2. d4 (White, Smith-Morra Gambit)
-		return "d2d4"
-	}
-
-	if strings.HasPrefix(u.fen, "rnbqkbnr/pp1ppppp/8/2p5/3PP3/8/PPP2PPP/RNBQKBNR b") {
-		// 1. e4 c5 2. d4 cxd4 (Black, Smith-Morra Gambit)
-		return "c5d4"
-	}
-
-	if strings.HasPrefix(u.fen, "rnbqkbnr/pp1ppppp/8/8/3pP3/8/PPP2PPP/RNBQKBNR w") {
-		// 1. e4 c5 2. d4 cxd4 3. c3 (White, Smith-Morra Gambit)
-		return "c2c3"
-	}
-
-	if strings.HasPrefix(u.fen, "rnbqkbnr/pp1ppppp/8/8/3pP3/2P5/PP3PPP/RNBQKBNR b") {
-		// 1. e4 c5 2. d4 cxd4 3. c3 dxc3 (Black, Smith-Morra Gambit)
-		return "d4c3"
-	}
-
-	if strings.HasPrefix(u.fen, "rnbqkbnr/pp1ppppp/8/8/4P3/2p5/PP3PPP/RNBQKBNR w") {
-		// 1. e4 c5 2. d4 cxd4 3. c3 dxc3 4. Nxc3 (White, Smith-Morra Gambit)
-		return "b1c3"
+	// agro games get Stockfish's reply
+	if !u.gameAgro {
+		return u.FirstReplyMove()
 	}
 
-	if strings.HasPrefix(u.fen, "rnbqkbnr/pp2pppp/3p4/8/4P3/2N5/PP3PPP/R1BQKBNR w KQkq -") {
-		// Smith-Morra: 4. ... d6 5. Bc4
-		return "f1c4"
-	}
+	return ""
+}
 
-	if strings.HasPrefix(u.fen, "rnbqkbnr/pp2pppp/3p4/8/2B1P3/2N5/PP3PPP/R1BQK1NR b KQkq -") {
-		return "b8c6" // Smith-Morra: 4. ... d6 5. Bc4 Nc6
-	}
+// FirstReplyMove returns Black's reply to White's first move from
+// firstReplyMap, or "" if the game isn't just after White's first move.
+func (u *UCI) FirstReplyMove() string {
+	u.moveListMtx.Lock()
+	b := u.board.clone()
+	u.moveListMtx.Unlock()
 
-	// TODO: we don't want to play an alternate move, but we want to respond to one
-	if strings.HasPrefix(u.fen, "rnbqkbnr/pp2pppp/3p4/8/2B1P3/2N5/PP3PPP/R1BQK1NR b KQkq -") {
-		return "e7e6" // Smith-Morra: 4. ... d6 5. Bc4 e6
+	if b.empty() || b.ActiveColor != Black {
+		return ""
 	}
 
-	// Reverse Morra
-	if strings.HasPrefix(u.fen, "rnbqkbnr/pppppppp/8/8/2P5/8/PP1PPPPP/RNBQKBNR b KQkq -") {
-		return "d2d4" // Reverse Morra: 1. c4 d4
-	}
+	return getFirstReply(&b, u.learning)
+}
 
-	if strings.HasPrefix(u.fen, "rnbqkbnr/ppp1pppp/8/3p4/2P5/8/PP1PPPPP/RNBQKBNR w KQkq -") {
-		return "c4d5" // Reverse Morra: 1. c4 d5 2. cxd5
-	}
+// atStartPos returns true if the game is at the starting position, whatever
+// move counters the GUI sent.
+func (u *UCI) atStartPos() bool {
+	u.moveListMtx.Lock()
+	b := u.board.clone()
+	u.moveListMtx.Unlock()
 
-	if strings.HasPrefix(u.fen, "rnbqkbnr/ppp1pppp/8/3P4/8/8/PP1PPPPP/RNBQKBNR b KQkq -") {
-		return "c7c6" // Reverse Morra: 1. c4 d5 2. cxd5 c6
-	}
+	return !b.empty() && b.Key() == startPosKey
+}
 
-	if strings.HasPrefix(u.fen, "rnbqkbnr/pp2pppp/2p5/3P4/8/8/PP1PPPPP/RNBQKBNR w KQkq -") {
-		return "d5c6" // Reverse Morra: 1. c4 d5 2. cxd5 c6 3. dxc6
+// PolyglotBookMove returns a move from the BookFile option's book, or "" if
+// there's no book or the position isn't in it.
+func (u *UCI) PolyglotBookMove() string {
+	if u.book == nil {
+		return ""
 	}
 
-	if strings.HasPrefix(u.fen, "rnbqkbnr/pp2pppp/2P5/8/8/8/PP1PPPPP/RNBQKBNR b KQkq -") {
-		return "b8c6" // Reverse Morra: 1. c4 d5 2. cxd5 c6 3. dxc6 Nxc6
-	}
+	u.moveListMtx.Lock()
+	b := u.board.clone()
+	u.moveListMtx.Unlock()
 
-	if strings.HasPrefix(u.fen, "r1bqkbnr/pp2pppp/2n5/8/8/2N5/PP1PPPPP/R1BQKBNR b KQkq -") {
-		// Reverse Morra: 1. c4 d5 2. cxd5 c6 3. dxc6 Nxc6 4. Nc3
-		// { White can play Nc3, d3, e3, g3, a3, h3, e4, Nf3 in this position }
-		// 4. ... a6 (alternative to e5 or Nf3)
-		return "a7a6"
+	if b.empty() {
+		return ""
 	}
 
-	/*if strings.HasPrefix(u.fen, "r1bqkbnr/1p2pppp/p1n5/8/8/2N5/PP1PPPPP/R1BQKBNR w KQkq -") {
-		// Reverse Morra: 1. c4 d5 2. cxd5 c6 3. dxc6 Nxc6 4. Nc3 a6 5. g3
-		// { White can play Nf3, d3, g3, f4, e3 in this position }
-		return "g2g3"
-	}*/
-
-	// d4 Opening
-	if strings.HasPrefix(u.fen, "rnbqkbnr/pppppppp/8/8/3P4/8/PPP1PPPP/RNBQKBNR b") {
-		return "g8f6" // 1. d4 Nf6
-	}
+	return pickWeighted(u.learning.Apply(&b, u.book.Moves(&b)))
+}
 
-	if strings.HasPrefix(u.fen, "rnbqkb1r/pppppppp/5n2/8/3P4/8/PPP1PPPP/RNBQKBNR w") {
-		return "c2c4" // 1. d4 Nf6 2. c4
-	}
+// CasualBookMove returns a move from the repertoire for the opponent, or "" if
+// the position isn't in it.
+func (u *UCI) CasualBookMove() string {
+	u.moveListMtx.Lock()
+	b := u.board.clone()
+	u.moveListMtx.Unlock()
 
-	if strings.HasPrefix(u.fen, "rnbqkb1r/pppppppp/5n2/8/2PP4/8/PP2PPPP/RNBQKBNR b") {
-		return "e7e6" // 1. d4 Nf6 2. c4 e6
+	if b.empty() {
+		return ""
 	}
 
-	if strings.HasPrefix(u.fen, "rnbqkb1r/pppppppp/5n2/8/3P4/5N2/PPP1PPPP/RNBQKB1R b") {
-		return "e7e6" // 1. d4 Nf6 2. Nf3 e6
-	}
+	return pickWeighted(u.learning.Apply(&b, u.repertoire.MovesFor(&b, u.opponent)))
+}
 
-	if strings.HasPrefix(u.fen, "rnbqkb1r/pppp1ppp/4pn2/8/2PP4/8/PP2PPPP/RNBQKBNR w") {
-		return "g2g3" // 1. d4 Nf6 2. c4 e6 3. g3 ( ... Nf3 )
-	}
+// bookCheck is a book move waiting on Stockfish's verdict, with the 'go'
+// arguments to search with if it's rejected. eval is the check's deepest
+// 'info', which is kept out of the move list, and stopped is set if the GUI
+// sent 'stop' during the check.
+type bookCheck struct {
+	move    string
+	goArgs  []string
+	eval    Info
+	stopped bool
+}
 
-	if strings.HasPrefix(u.fen, "rnbqkb1r/pppp1ppp/4pn2/8/2PP4/5N2/PP2PPPP/RNBQKB1R b") {
-		return "b7b6" // 1. d4 Nf6 2. Nf3 e6 3. c4 b6
+// verifyBookMove has Stockfish search only the book move before it's played,
+// so a book line which has gone wrong, for example by transposition, isn't
+// played blindly. finishBookCheck plays it or searches with v.
+func (u *UCI) verifyBookMove(move string, v []string) {
+	u.moveListMtx.Lock()
+	if u.board.empty() {
+		u.moveListMtx.Unlock()
+		u.playBookMove(move)
+		return
 	}
+	u.bookCheck = &bookCheck{move: move, goArgs: v}
+	u.moveListMtx.Unlock()
 
-	if strings.HasPrefix(u.fen, "rnbqk2r/p1pp1ppp/1p2pn2/8/1bPP4/5NP1/PP2PP1P/RNBQKB1R w") {
-		return "c1d2" // 1. d4 Nf6 2. Nf3 e6 3. c4 b6 4. g3 Bb4+ 5. Bd2
-	}
+	u.sf.Write(fmt.Sprintf("go movetime %d searchmoves %s", bookCheckMoveTime, move))
+}
 
-	if strings.HasPrefix(u.fen, "rnbqk2r/p1pp1ppp/1p2pn2/8/1bPP4/5NP1/PP1BPP1P/RN1QKB1R b") {
-		return "b4e7" // 1. d4 Nf6 2. Nf3 e6 3. c4 b6 4. g3 Bb4+ 5. Bd2 Be7
-	}
+// finishBookCheck handles Stockfish's bestmove for a book move check: the book
+// move is played if its eval is at least BookEvalFloor, otherwise we search
+// for a move as if there were no book. If the GUI stopped the check there's no
+// time to search, so the book move is played anyway. It returns false if no
+// book move was being checked.
+func (u *UCI) finishBookCheck() bool {
+	u.moveListMtx.Lock()
+	check := u.bookCheck
+	if check == nil {
+		u.moveListMtx.Unlock()
+		return false
+	}
+	u.bookCheck = nil
+	floor := u.bookEvalFloor
+	u.moveListMtx.Unlock()
+
+	eval := check.eval
+	if eval.Mate < 0 || (eval.Mate == 0 && eval.Score < floor) {
+		if check.stopped {
+			// searchmoves only searched the book move, so it's also Stockfish's
+			u.logInfo(fmt.Sprintf("book_move_stopped: %s eval: %d mate: %d floor: %d", check.move, eval.Score, eval.Mate, floor))
+			u.playBookMove(check.move)
+			return true
+		}
 
-	if strings.HasPrefix(u.fen, "rnbqkb1r/p1pp1ppp/1p2pn2/8/2PP4/5NP1/PP2PP1P/RNBQKB1R b") {
-		return "c8a6" // 1. d4 Nf6 2. Nf3 e6 3. c4 b6 4. g3 Ba6
+		u.logInfo(fmt.Sprintf("book_move_rejected: %s eval: %d mate: %d floor: %d", check.move, eval.Score, eval.Mate, floor))
+		u.WriteLine(fmt.Sprintf("info string book move %s rejected, eval %s", check.move, eval.scoreString()))
+		u.search(check.goArgs)
+		return true
 	}
 
-	if strings.HasPrefix(u.fen, "rn1qkb1r/p1pp1ppp/bp2pn2/8/2PP4/1P3NP1/P3PP1P/RNBQKB1R b") {
-		return "d7d5" // 1. d4 Nf6 2. Nf3 e6 3. c4 b6 4. g3 Ba6 5. b3 d5
-	}
+	u.playBookMove(check.move)
+	return true
+}
 
-	if strings.HasPrefix(u.fen, "rn1qkb1r/p1p2ppp/bp2pn2/3p4/2PP4/1P3NP1/P3PPBP/RNBQK2R b") {
-		return "b8d7" // 1. d4 Nf6 2. Nf3 e6 3. c4 b6 4. g3 Ba6 5. b3 d5 6. Bg2 Nbd7
+// stopBookCheck records that the GUI sent 'stop' while a book move was being
+// checked, see finishBookCheck.
+func (u *UCI) stopBookCheck() {
+	u.moveListMtx.Lock()
+	if u.bookCheck != nil {
+		u.bookCheck.stopped = true
 	}
+	u.moveListMtx.Unlock()
+}
 
-	return ""
+// playBookMove plays a move from the book.
+func (u *UCI) playBookMove(move string) {
+	u.recordBookMove(move)
+	u.logInfo(fmt.Sprintf("book_move: %s", move))
+	u.WriteLine("bestmove " + move)
 }
@@ -16,7 +16,7 @@ func TestFirstMove(t *testing.T) {
 	const runs = 10_000
 
 	for i := 0; i < runs; i++ {
-		uci := getFirstMove()
+		uci := getFirstMove(nil)
 		m[uci] += 1
 	}
 
@@ -39,3 +39,86 @@ func TestFirstMove(t *testing.T) {
 		fmt.Printf("%s: %4d %4.1f%%\n", item.uci, item.freq, float64(item.freq)/runs*100)
 	}
 }
+
+func TestFirstReplyMap(t *testing.T) {
+	for first, replies := range firstReplyMap {
+		first, replies := first, replies
+		t.Run(first, func(t *testing.T) {
+			b := FENtoBoard(startPosFEN)
+
+			// act
+			b.Moves(first)
+
+			// assert
+			if !containsString(firstMoveTable(), first) {
+				t.Errorf("%s isn't in firstMoveMap", first)
+			}
+			total := 0
+			for _, reply := range replies {
+				if !b.IsLegalMove(reply.uci) {
+					t.Errorf("%s is not legal after %s", reply.uci, first)
+				}
+				total += reply.freq
+			}
+			if total == 0 {
+				t.Errorf("no reply to %s has a weight", first)
+			}
+			if got := firstReplies[b.Key()]; len(got) != len(replies) {
+				t.Errorf("want %d replies to %s by key got: %v", len(replies), first, got)
+			}
+		})
+	}
+}
+
+// firstMoveTable returns the moves in firstMoveMap.
+func firstMoveTable() []string {
+	var moves []string
+	for _, item := range firstMoveMap {
+		moves = append(moves, item.uci)
+	}
+	return moves
+}
+
+func TestFirstReplyMove(t *testing.T) {
+	defer func(saved map[uint64][]BookMove) { firstReplies = saved }(firstReplies)
+	firstReplies = makeFirstReplies(map[string][]*firstMove{
+		"b2b4": {{uci: "e7e5", freq: 30}, {uci: "d7d5", freq: 25}, {uci: "g8f6", freq: 20}},
+	})
+
+	// arrange
+	cases := []struct {
+		name  string
+		fen   string
+		agro  bool
+		want  []string
+		empty bool
+	}{
+		{name: "1. b4", fen: "rnbqkbnr/pppppppp/8/8/1P6/8/P1PPPPPP/RNBQKBNR b KQkq b3 0 1", want: []string{"e7e5", "d7d5", "g8f6"}},
+		{name: "move counters are ignored", fen: "rnbqkbnr/pppppppp/8/8/1P6/8/P1PPPPPP/RNBQKBNR b KQkq - 4 7", want: []string{"e7e5", "d7d5", "g8f6"}},
+		{name: "agro asks Stockfish", fen: "rnbqkbnr/pppppppp/8/8/1P6/8/P1PPPPPP/RNBQKBNR b KQkq b3 0 1", agro: true, empty: true},
+		{name: "a first move without replies asks Stockfish", fen: "rnbqkbnr/pppppppp/8/8/7P/8/PPPPPPP1/RNBQKBNR b KQkq h3 0 1", empty: true},
+		{name: "not after the first move", fen: "rnbqkbnr/pppp1ppp/8/4p3/1P6/8/P1PPPPPP/RNBQKBNR w KQkq e6 0 2", empty: true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			u := New("trollfish", "test")
+			u.board = FENtoBoard(c.fen)
+			u.gameAgro = c.agro
+
+			// act
+			got := u.BookMove()
+
+			// assert
+			if c.empty {
+				if got != "" {
+					t.Errorf("want no book move got: %s", got)
+				}
+				return
+			}
+			if !containsString(c.want, got) {
+				t.Errorf("want one of %v got: '%s'", c.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package uci
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// opponent classes, which are also the repertoire's sections
+const (
+	opponentHuman    = "human"
+	opponentComputer = "computer"
+)
+
+// Opponent is who we're playing, from the UCI_Opponent option.
+type Opponent struct {
+	// Title is e.g. "GM", or empty if the opponent has none
+	Title string
+
+	// Rating is 0 if it's unknown
+	Rating int
+
+	// Kind is "human" or "computer", which is also the opponent's repertoire
+	// section, or empty if UCI_Opponent hasn't said
+	Kind string
+
+	Name string
+}
+
+// ParseOpponent parses the UCI_Opponent option's value:
+// <title> <rating> <computer|human> <name>, where title and rating can be
+// 'none', e.g. "GM 2800 human Gary Kasparov" or "none none computer Shredder".
+func ParseOpponent(value string) (Opponent, error) {
+	fields := strings.Fields(value)
+	if len(fields) < 3 {
+		return Opponent{}, fmt.Errorf("opponent '%s' should be '<title> <rating> <computer|human> <name>'", value)
+	}
+
+	var o Opponent
+	if fields[0] != "none" {
+		o.Title = fields[0]
+	}
+
+	if fields[1] != "none" {
+		rating, err := strconv.Atoi(fields[1])
+		if err != nil || rating < 0 {
+			return Opponent{}, fmt.Errorf("opponent '%s' has an invalid rating '%s'", value, fields[1])
+		}
+		o.Rating = rating
+	}
+
+	switch fields[2] {
+	case opponentComputer, opponentHuman:
+		o.Kind = fields[2]
+	default:
+		return Opponent{}, fmt.Errorf("opponent '%s' should be 'computer' or 'human', not '%s'", value, fields[2])
+	}
+
+	o.Name = strings.Join(fields[3:], " ")
+
+	return o, nil
+}
+
+func (o Opponent) String() string {
+	var parts []string
+	if o.Title != "" {
+		parts = append(parts, o.Title)
+	}
+	if o.Name != "" {
+		parts = append(parts, o.Name)
+	}
+	if o.Rating != 0 {
+		parts = append(parts, fmt.Sprintf("(%d)", o.Rating))
+	}
+	if o.Kind != "" {
+		parts = append(parts, o.Kind)
+	}
+	if len(parts) == 0 {
+		return "unknown"
+	}
+	return strings.Join(parts, " ")
+}
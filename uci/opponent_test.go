@@ -0,0 +1,137 @@
+package uci
+
+import (
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseOpponent(t *testing.T) {
+	// arrange
+	cases := []struct {
+		value    string
+		want     Opponent
+		expError string
+	}{
+		{value: "GM 2800 human Gary Kasparov", want: Opponent{Title: "GM", Rating: 2800, Kind: "human", Name: "Gary Kasparov"}},
+		{value: "none none computer Shredder", want: Opponent{Kind: "computer", Name: "Shredder"}},
+		{value: "BOT 3000 computer", want: Opponent{Title: "BOT", Rating: 3000, Kind: "computer"}},
+		{value: "none 1500", expError: "opponent 'none 1500' should be '<title> <rating> <computer|human> <name>'"},
+		{value: "none x human Bob", expError: "opponent 'none x human Bob' has an invalid rating 'x'"},
+		{value: "none none robot Bob", expError: "opponent 'none none robot Bob' should be 'computer' or 'human', not 'robot'"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.value, func(t *testing.T) {
+			// act
+			got, err := ParseOpponent(c.value)
+
+			// assert
+			if c.expError != "" {
+				if err == nil || err.Error() != c.expError {
+					t.Fatalf("want error: %s got: %v", c.expError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != c.want {
+				t.Errorf("want: %+v got: %+v", c.want, got)
+			}
+		})
+	}
+}
+
+func TestSetOptionOpponent(t *testing.T) {
+	// arrange
+	cases := []struct {
+		line    string
+		want    Opponent
+		wantOut string
+	}{
+		{line: "setoption name UCI_Opponent value IM 2450 human Jane Q Public", want: Opponent{Title: "IM", Rating: 2450, Kind: "human", Name: "Jane Q Public"}},
+		{line: "setoption name UCI_Opponent value none none computer Stockfish 15", want: Opponent{Kind: "computer", Name: "Stockfish 15"}},
+		{line: "setoption name UCI_Opponent value human", wantOut: "info string ERR: opponent 'human' should be '<title> <rating> <computer|human> <name>'\n"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.line, func(t *testing.T) {
+			var out strings.Builder
+			u := New("trollfish", "test")
+			u.log = nopWriteCloser{io.Discard}
+			u.out = &out
+
+			// act
+			u.parseLine(c.line)
+
+			// assert
+			if u.opponent != c.want {
+				t.Errorf("want: %+v got: %+v", c.want, u.opponent)
+			}
+			if out.String() != c.wantOut {
+				t.Errorf("output want: '%s' got: '%s'", c.wantOut, out.String())
+			}
+		})
+	}
+}
+
+func TestDefaultOpponent(t *testing.T) {
+	// arrange
+	u := New("trollfish", "test")
+	u.board = FENtoBoard(startPosFEN)
+	u.board.Moves("e2e4", "e7e5")
+
+	// act
+	got := u.CasualBookMove()
+
+	// assert
+	if u.opponent != (Opponent{}) {
+		t.Errorf("want an unknown opponent got: %+v", u.opponent)
+	}
+	if got != "" {
+		t.Errorf("want no human-only lines without UCI_Opponent got: %s", got)
+	}
+}
+
+func TestRepertoireOpponents(t *testing.T) {
+	rep := mustParseRepertoire(defaultRepertoire)
+	var unknown Opponent
+	human := Opponent{Kind: opponentHuman}
+	bot := Opponent{Kind: opponentComputer}
+
+	// arrange
+	cases := []struct {
+		name     string
+		moves    string
+		opponent Opponent
+		want     []BookMove
+	}{
+		{name: "Wayward Queen against humans", moves: "e2e4 e7e5", opponent: human, want: []BookMove{{Move: "d1h5", Weight: 1}}},
+		{name: "Nf3 against computers", moves: "e2e4 e7e5", opponent: bot, want: []BookMove{{Move: "g1f3", Weight: 1}}},
+		{name: "Englund Gambit against humans", moves: "d2d4", opponent: human, want: []BookMove{{Move: "e7e5", Weight: 1}, {Move: "g8f6", Weight: 0}}},
+		{name: "Nf6 against computers", moves: "d2d4", opponent: bot, want: []BookMove{{Move: "g8f6", Weight: 1}}},
+		{name: "Englund Gambit 7. Rb1 Qxc3 against humans", moves: "d2d4 e7e5 d4e5 b8c6 g1f3 d8e7 c1g5 e7b4 g5d2 b4b2 b1c3 f8b4 a1b1", opponent: human, want: []BookMove{{Move: "b2c3", Weight: 1}}},
+		{name: "lines for anyone", moves: "c2c4", opponent: bot, want: []BookMove{{Move: "d7d5", Weight: 1}}},
+		{name: "no Wayward Queen against an unknown opponent", moves: "e2e4 e7e5", opponent: unknown, want: nil},
+		{name: "no Englund Gambit against an unknown opponent", moves: "d2d4", opponent: unknown, want: []BookMove{{Move: "g8f6", Weight: 0}}},
+		{name: "no 7. Rb1 Qxc3 against an unknown opponent", moves: "d2d4 e7e5 d4e5 b8c6 g1f3 d8e7 c1g5 e7b4 g5d2 b4b2 b1c3 f8b4 a1b1", opponent: unknown, want: nil},
+		{name: "lines for anyone against an unknown opponent", moves: "c2c4", opponent: unknown, want: []BookMove{{Move: "d7d5", Weight: 1}}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			b := FENtoBoard(startPosFEN)
+			b.Moves(strings.Fields(c.moves)...)
+
+			// act
+			got := rep.MovesFor(&b, c.opponent)
+
+			// assert
+			if !reflect.DeepEqual(got, c.want) {
+				t.Errorf("want: %v got: %v", c.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package uci
+
+import "fmt"
+
+type PerftResult struct {
+	Move  string
+	Nodes int
+}
+
+func (r PerftResult) String() string {
+	return fmt.Sprintf("%s: %d", r.Move, r.Nodes)
+}
+
+// Perft counts the leaf nodes of the legal move tree to the given depth.
+func (b *Board) Perft(depth int) int {
+	if depth <= 0 {
+		return 1
+	}
+
+	moves := b.GenerateMoves()
+	if depth == 1 {
+		return len(moves)
+	}
+
+	var nodes int
+	for _, m := range moves {
+		c := *b
+		c.MakeMove(m)
+		nodes += c.Perft(depth - 1)
+	}
+
+	return nodes
+}
+
+// Divide returns the perft node count below each legal move, the same
+// breakdown Stockfish prints for 'go perft'.
+func (b *Board) Divide(depth int) []PerftResult {
+	if depth <= 0 {
+		return nil
+	}
+
+	moves := b.GenerateMoves()
+	results := make([]PerftResult, 0, len(moves))
+	for _, m := range moves {
+		c := *b
+		c.MakeMove(m)
+		results = append(results, PerftResult{Move: m.String(), Nodes: c.Perft(depth - 1)})
+	}
+
+	return results
+}
@@ -0,0 +1,150 @@
+package uci
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestPerft(t *testing.T) {
+	// arrange
+	// https://www.chessprogramming.org/Perft_Results
+	cases := []struct {
+		name     string
+		fen      string
+		chess960 bool
+		nodes    []int // indexed by depth-1
+	}{
+		{
+			name:  "initial position",
+			fen:   startPosFEN,
+			nodes: []int{20, 400, 8902, 197281},
+		},
+		{
+			name:  "kiwipete",
+			fen:   "r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1",
+			nodes: []int{48, 2039, 97862},
+		},
+		{
+			name:  "position 3",
+			fen:   "8/2p5/3p4/KP5r/1R3p1k/8/4P1P1/8 w - - 0 1",
+			nodes: []int{14, 191, 2812, 43238},
+		},
+		{
+			name:  "position 4",
+			fen:   "r3k2r/Pppp1ppp/1b3nbN/nP6/BBP1P3/q4N2/Pp1P2PP/R2Q1RK1 w kq - 0 1",
+			nodes: []int{6, 264, 9467},
+		},
+		{
+			name:  "position 4 mirrored",
+			fen:   "r2q1rk1/pP1p2pp/Q4n2/bbp1p3/Np6/1B3NBn/pPPP1PPP/R3K2R b KQ - 0 1",
+			nodes: []int{6, 264, 9467},
+		},
+		{
+			name:  "position 5",
+			fen:   "rnbq1k1r/pp1Pbppp/2p5/8/2B5/8/PPP1NnPP/RNBQK2R w KQ - 1 8",
+			nodes: []int{44, 1486, 62379},
+		},
+		{
+			name:  "position 6",
+			fen:   "r4rk1/1pp1qppp/p1np1n2/2b1p1B1/2B1P1b1/P1NP1N2/1PP1QPPP/R4RK1 w - - 0 10",
+			nodes: []int{46, 2079, 89890},
+		},
+		// https://www.chessprogramming.org/Chess960_Perft_Results
+		{
+			name:     "chess960 position 1",
+			fen:      "bqnb1rkr/pp3ppp/3ppn2/2p5/5P2/P2P4/NPP1P1PP/BQ1BNRKR w HFhf - 2 9",
+			chess960: true,
+			nodes:    []int{21, 528, 12189, 326672},
+		},
+		{
+			name:     "chess960 position 2",
+			fen:      "2nnrbkr/p1qppppp/8/1ppb4/6PP/3PP3/PPP2P2/BQNNRBKR w HEhe - 1 9",
+			chess960: true,
+			nodes:    []int{21, 807, 18002, 667366},
+		},
+		{
+			name:     "chess960 position 3",
+			fen:      "b1q1rrkb/pppppppp/3nn3/8/P7/1PPP4/4PPPP/BQNNRKRB w GE - 1 9",
+			chess960: true,
+			nodes:    []int{20, 479, 10471, 273318},
+		},
+		{
+			name:     "chess960 position 4",
+			fen:      "qbbnnrkr/2pp2pp/p7/1p2pp2/8/P3PP2/1PPP1KPP/QBBNNR1R w hf - 0 9",
+			chess960: true,
+			nodes:    []int{22, 593, 13440, 382958},
+		},
+		{
+			name:     "chess960 position 5",
+			fen:      "1nbbnrkr/p1p1ppp1/3p4/1p3P1p/3Pq2P/8/PPP1P1P1/QNBBNRKR w HFhf - 0 9",
+			chess960: true,
+			nodes:    []int{28, 1120, 31058, 1171749},
+		},
+	}
+
+	for _, c := range cases {
+		for i, want := range c.nodes {
+			depth := i + 1
+			if testing.Short() && want > 10_000 {
+				continue
+			}
+
+			t.Run(fmt.Sprintf("%s depth %d", c.name, depth), func(t *testing.T) {
+				// act
+				b := FENtoBoard(c.fen)
+				b.Chess960 = c.chess960
+				got := b.Perft(depth)
+
+				// assert
+				if got != want {
+					var divide string
+					for _, r := range b.Divide(depth) {
+						divide += r.String() + "\n"
+					}
+					t.Errorf("want: %d got: %d\n%s", want, got, divide)
+				}
+			})
+		}
+	}
+}
+
+func TestDivide(t *testing.T) {
+	// arrange
+	b := FENtoBoard("r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1")
+	want := map[string]int{"e1g1": 43, "e1c1": 43, "d5e6": 46, "g2h3": 43, "e5f7": 44}
+
+	// act
+	results := b.Divide(2)
+
+	// assert
+	var total int
+	for _, r := range results {
+		total += r.Nodes
+		if n, ok := want[r.Move]; ok && n != r.Nodes {
+			t.Errorf("%s, want: %d got: %d", r.Move, n, r.Nodes)
+		}
+	}
+	if total != 2039 {
+		t.Errorf("total, want: %d got: %d", 2039, total)
+	}
+}
+
+func BenchmarkPerft(b *testing.B) {
+	cases := []struct {
+		name  string
+		fen   string
+		depth int
+	}{
+		{name: "initial position", fen: startPosFEN, depth: 3},
+		{name: "kiwipete", fen: "r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1", depth: 2},
+	}
+
+	for _, c := range cases {
+		b.Run(c.name, func(b *testing.B) {
+			board := FENtoBoard(c.fen)
+			for i := 0; i < b.N; i++ {
+				board.Perft(c.depth)
+			}
+		})
+	}
+}
@@ -0,0 +1,658 @@
+package uci
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Game is a game read from a PGN file. The moves have been replayed from the
+// starting position, so every move is known to be legal.
+type Game struct {
+	Tags []Tag
+
+	// Comment is the comment before the first move, if any.
+	Comment string
+
+	Moves []GameMove
+
+	// Result is the game termination marker: "1-0", "0-1", "1/2-1/2" or "*".
+	Result string
+
+	start Board
+}
+
+// Tag is a PGN tag pair, e.g. [White "trollfish"].
+type Tag struct {
+	Name  string
+	Value string
+}
+
+// GameMove is a move with the annotations which follow it.
+type GameMove struct {
+	// Move is in UCI notation, SAN as written in the file.
+	Move string
+	SAN  string
+
+	Comments []string
+
+	// NAGs are the numeric annotation glyphs, with suffix annotations such as
+	// "!?" converted to their NAG ($5).
+	NAGs []int
+
+	// Variations are the alternatives to this move, each starting from the
+	// position before it. They're only kept if the reader's KeepVariations
+	// is set; a comment at the start of a variation is kept on its first
+	// move.
+	Variations [][]GameMove
+}
+
+// Tag returns the value of the tag, or "" if the game doesn't have it.
+func (g *Game) Tag(name string) string {
+	for _, t := range g.Tags {
+		if t.Name == name {
+			return t.Value
+		}
+	}
+	return ""
+}
+
+// SetTag sets the value of the tag, adding it if the game doesn't have it.
+func (g *Game) SetTag(name, value string) {
+	for i, t := range g.Tags {
+		if t.Name == name {
+			g.Tags[i].Value = value
+			return
+		}
+	}
+	g.Tags = append(g.Tags, Tag{Name: name, Value: value})
+}
+
+// StartBoard returns the position the game starts from, the FEN tag if it has
+// one, otherwise the standard starting position.
+func (g *Game) StartBoard() Board {
+	if g.start.empty() {
+		return FENtoBoard(startPosFEN)
+	}
+	return g.start
+}
+
+// Replay plays the main line from the starting position, calling visit with
+// the position before each move.
+func (g *Game) Replay(visit func(b *Board, m GameMove)) Board {
+	b := g.StartBoard()
+	for _, m := range g.Moves {
+		visit(&b, m)
+		b.Moves(m.Move)
+	}
+	return b
+}
+
+// WritePGN writes the game in PGN export format: the tags in order, then the
+// movetext wrapped at 80 columns. Moves without SAN are converted from UCI.
+func (g *Game) WritePGN(w io.Writer) error {
+	var pgn strings.Builder
+	for _, t := range g.Tags {
+		value := strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(t.Value)
+		pgn.WriteString(fmt.Sprintf("[%s \"%s\"]\n", t.Name, value))
+	}
+	pgn.WriteString("\n")
+
+	var tokens []string
+	if g.Comment != "" {
+		tokens = append(tokens, "{ "+g.Comment+" }")
+	}
+	tokens = appendMoveText(tokens, g.StartBoard(), g.Moves)
+
+	result := g.Result
+	if result == "" {
+		result = "*"
+	}
+	tokens = append(tokens, result)
+
+	width := 0
+	for _, tok := range tokens {
+		if width != 0 && width+1+len(tok) > 80 {
+			pgn.WriteString("\n")
+			width = 0
+		}
+		if width != 0 {
+			pgn.WriteString(" ")
+			width++
+		}
+		pgn.WriteString(tok)
+		width += len(tok)
+	}
+	pgn.WriteString("\n\n")
+
+	_, err := io.WriteString(w, pgn.String())
+	return err
+}
+
+// appendMoveText appends the moves played from b as PGN tokens. Black's move
+// gets a move number of its own ("12...") at the start of a line of moves or
+// after a comment or variation.
+func appendMoveText(tokens []string, b Board, moves []GameMove) []string {
+	number := true
+	for _, m := range moves {
+		if b.ActiveColor == White {
+			tokens = append(tokens, fmt.Sprintf("%d.", b.FullMove))
+		} else if number {
+			tokens = append(tokens, fmt.Sprintf("%d...", b.FullMove))
+		}
+		number = false
+
+		san := m.SAN
+		if san == "" {
+			san = b.sanOrUCI(m.Move)
+		}
+		tokens = append(tokens, san)
+
+		for _, nag := range m.NAGs {
+			tokens = append(tokens, fmt.Sprintf("$%d", nag))
+		}
+		for _, comment := range m.Comments {
+			tokens = append(tokens, "{ "+comment+" }")
+			number = true
+		}
+		for _, variation := range m.Variations {
+			if len(variation) == 0 {
+				continue
+			}
+			start := len(tokens)
+			tokens = appendMoveText(tokens, b, variation)
+			tokens[start] = "(" + tokens[start]
+			tokens[len(tokens)-1] += ")"
+			number = true
+		}
+
+		b.Moves(m.Move)
+	}
+	return tokens
+}
+
+// suffixNAGs converts move suffix annotations to NAGs.
+var suffixNAGs = map[string]int{"!": 1, "?": 2, "!!": 3, "??": 4, "!?": 5, "?!": 6}
+
+// PGNReader reads games from a PGN file one at a time.
+type PGNReader struct {
+	// KeepVariations keeps the variations ('(' ... ')') with each move,
+	// otherwise they're skipped.
+	KeepVariations bool
+
+	lex   pgnLexer
+	games int
+}
+
+// NewPGNReader returns a reader for the PGN games in r.
+func NewPGNReader(r io.Reader) *PGNReader {
+	return &PGNReader{lex: pgnLexer{r: bufio.NewReader(r), line: 1, lineStart: true}}
+}
+
+// ReadPGN reads all the games in r, skipping variations.
+func ReadPGN(r io.Reader) ([]*Game, error) {
+	var games []*Game
+	pr := NewPGNReader(r)
+	for {
+		g, err := pr.Next()
+		if err == io.EOF {
+			return games, nil
+		}
+		if err != nil {
+			return games, err
+		}
+		games = append(games, g)
+	}
+}
+
+// Next returns the next game, or io.EOF after the last one. If a game can't be
+// read, for example because of an illegal move, the error names the game and
+// the next call carries on with the game after it. An error reading r can't be
+// carried on from: it's returned by every call after it too, see Err.
+func (r *PGNReader) Next() (*Game, error) {
+	if err := r.Err(); err != nil {
+		return nil, err
+	}
+
+	var g Game
+	r.games++
+
+	for {
+		tok, err := r.lex.next()
+		if err != nil {
+			return nil, r.fail(&g, err)
+		}
+		if tok.kind == pgnEOF {
+			if len(g.Tags) == 0 {
+				return nil, io.EOF
+			}
+			break
+		}
+		if tok.kind != pgnTag {
+			r.lex.unread(tok)
+			break
+		}
+		g.Tags = append(g.Tags, Tag{Name: tok.text, Value: tok.value})
+	}
+
+	b := FENtoBoard(startPosFEN)
+	if fen := g.Tag("FEN"); fen != "" {
+		var err error
+		if b, err = ParseFEN(fen); err != nil {
+			return nil, r.fail(&g, err)
+		}
+	}
+	b.Chess960 = strings.EqualFold(g.Tag("Variant"), "chess960")
+	g.start = b
+
+	moves, comment, err := r.readMoves(b)
+	if err != nil {
+		return nil, r.fail(&g, err)
+	}
+	g.Moves, g.Comment = moves, comment
+
+	tok, err := r.lex.next()
+	if err != nil {
+		return nil, r.fail(&g, err)
+	}
+	switch tok.kind {
+	case pgnResult:
+		g.Result = tok.text
+	case pgnClose:
+		return nil, r.fail(&g, fmt.Errorf("line %d: unexpected ')'", tok.line))
+	default:
+		// a missing termination marker; the next game's tags follow
+		r.lex.unread(tok)
+		g.Result = g.Tag("Result")
+	}
+
+	return &g, nil
+}
+
+// readMoves reads a line of moves played from b up to the result or the end
+// of the variation, which are left for the caller. It returns the comment
+// before the first move.
+func (r *PGNReader) readMoves(b Board) ([]GameMove, string, error) {
+	var moves []GameMove
+	var comment string
+	var before Board
+
+	for {
+		tok, err := r.lex.next()
+		if err != nil {
+			return nil, "", err
+		}
+
+		switch tok.kind {
+		case pgnEOF, pgnTag, pgnResult, pgnClose:
+			r.lex.unread(tok)
+			return moves, comment, nil
+		case pgnComment:
+			if len(moves) == 0 {
+				comment = strings.TrimSpace(comment + " " + tok.text)
+			} else {
+				last := &moves[len(moves)-1]
+				last.Comments = append(last.Comments, tok.text)
+			}
+		case pgnNAG:
+			if len(moves) == 0 {
+				return nil, "", fmt.Errorf("line %d: NAG '$%d' before the first move", tok.line, tok.nag)
+			}
+			last := &moves[len(moves)-1]
+			last.NAGs = append(last.NAGs, tok.nag)
+		case pgnOpen:
+			if len(moves) == 0 {
+				return nil, "", fmt.Errorf("line %d: variation before the first move", tok.line)
+			}
+			if !r.KeepVariations {
+				if err := r.skipVariation(); err != nil {
+					return nil, "", err
+				}
+				continue
+			}
+
+			variation, varComment, err := r.readMoves(before)
+			if err != nil {
+				return nil, "", err
+			}
+			if closeTok, err := r.lex.next(); err != nil {
+				return nil, "", err
+			} else if closeTok.kind != pgnClose {
+				return nil, "", fmt.Errorf("line %d: variation isn't closed", closeTok.line)
+			}
+			if len(variation) != 0 {
+				if varComment != "" {
+					variation[0].Comments = append([]string{varComment}, variation[0].Comments...)
+				}
+				last := &moves[len(moves)-1]
+				last.Variations = append(last.Variations, variation)
+			}
+		case pgnMove:
+			san, nags := splitSuffix(tok.text)
+			move, err := b.ParseSAN(san)
+			if err != nil {
+				return nil, "", fmt.Errorf("line %d: %v", tok.line, err)
+			}
+			before = b
+			b.Moves(move)
+			moves = append(moves, GameMove{Move: move, SAN: san, NAGs: nags})
+		}
+	}
+}
+
+// skipVariation skips to the ')' closing a variation, including any variations
+// nested in it.
+func (r *PGNReader) skipVariation() error {
+	depth := 1
+	for depth > 0 {
+		tok, err := r.lex.next()
+		if err != nil {
+			return err
+		}
+		switch tok.kind {
+		case pgnOpen:
+			depth++
+		case pgnClose:
+			depth--
+		case pgnEOF, pgnTag, pgnResult:
+			return fmt.Errorf("line %d: variation isn't closed", tok.line)
+		}
+	}
+	return nil
+}
+
+// skipGame skips the rest of a game after an error, up to its termination
+// marker or the next game's tags.
+func (r *PGNReader) skipGame() {
+	for {
+		tok, err := r.lex.next()
+		if err != nil || tok.kind == pgnEOF || tok.kind == pgnResult {
+			return
+		}
+		if tok.kind == pgnTag {
+			r.lex.unread(tok)
+			return
+		}
+	}
+}
+
+// Err returns the error reading the PGN, if there was one, after which Next
+// can't carry on.
+func (r *PGNReader) Err() error {
+	return r.lex.err
+}
+
+// fail returns the error for a game which can't be read, after skipping the
+// rest of it. A read error is returned as it is.
+func (r *PGNReader) fail(g *Game, err error) error {
+	if r.Err() == nil {
+		r.skipGame()
+	}
+	if readErr := r.Err(); readErr != nil {
+		return readErr
+	}
+	return r.gameError(g, err)
+}
+
+func (r *PGNReader) gameError(g *Game, err error) error {
+	if white, black := g.Tag("White"), g.Tag("Black"); white != "" || black != "" {
+		return fmt.Errorf("game %d '%s - %s': %v", r.games, white, black, err)
+	}
+	return fmt.Errorf("game %d: %v", r.games, err)
+}
+
+// splitSuffix removes the suffix annotation from a SAN move, returning it as
+// a NAG. Check and mate markers are kept.
+func splitSuffix(san string) (string, []int) {
+	i := strings.IndexAny(san, "!?")
+	if i == -1 {
+		return san, nil
+	}
+	if nag, ok := suffixNAGs[san[i:]]; ok {
+		return san[:i], []int{nag}
+	}
+	return san[:i], nil
+}
+
+type pgnTokenKind int
+
+const (
+	pgnEOF pgnTokenKind = iota
+	pgnTag
+	pgnComment
+	pgnNAG
+	pgnOpen
+	pgnClose
+	pgnMove
+	pgnResult
+)
+
+type pgnToken struct {
+	kind  pgnTokenKind
+	text  string // tag name, comment, move or result
+	value string // tag value
+	nag   int
+	line  int
+}
+
+// pgnLexer splits PGN text into tokens. Move numbers are dropped, and lines
+// starting with '%' are skipped as the standard asks. err is the first error
+// reading r other than io.EOF.
+type pgnLexer struct {
+	r         *bufio.Reader
+	line      int
+	lineStart bool
+	peeked    []pgnToken
+	err       error
+}
+
+func (l *pgnLexer) unread(tok pgnToken) {
+	l.peeked = append(l.peeked, tok)
+}
+
+func (l *pgnLexer) readRune() (rune, error) {
+	if l.err != nil {
+		return 0, l.err
+	}
+	c, _, err := l.r.ReadRune()
+	if err != nil {
+		if err != io.EOF {
+			l.err = err
+		}
+		return 0, err
+	}
+	l.lineStart = c == '\n'
+	if c == '\n' {
+		l.line++
+	}
+	return c, nil
+}
+
+func (l *pgnLexer) unreadRune(c rune) {
+	_ = l.r.UnreadRune()
+	if c == '\n' {
+		l.line--
+	}
+}
+
+func (l *pgnLexer) next() (pgnToken, error) {
+	if n := len(l.peeked); n != 0 {
+		tok := l.peeked[n-1]
+		l.peeked = l.peeked[:n-1]
+		return tok, nil
+	}
+
+	for {
+		lineStart := l.lineStart
+		c, err := l.readRune()
+		if err == io.EOF {
+			return pgnToken{kind: pgnEOF, line: l.line}, nil
+		}
+		if err != nil {
+			return pgnToken{}, err
+		}
+
+		line := l.line
+		switch {
+		case unicode.IsSpace(c) || c == '\ufeff':
+			continue
+		case c == '%' && lineStart, c == ';':
+			if _, err := l.readUntil('\n'); err != nil {
+				return pgnToken{}, err
+			}
+		case c == '{':
+			text, err := l.readUntil('}')
+			if err != nil {
+				return pgnToken{}, err
+			}
+			return pgnToken{kind: pgnComment, text: strings.Join(strings.Fields(text), " "), line: line}, nil
+		case c == '[':
+			text, err := l.readTag()
+			if err != nil {
+				return pgnToken{}, err
+			}
+			name, value, err := parseTag(text)
+			if err != nil {
+				return pgnToken{}, fmt.Errorf("line %d: %v", line, err)
+			}
+			return pgnToken{kind: pgnTag, text: name, value: value, line: line}, nil
+		case c == '(':
+			return pgnToken{kind: pgnOpen, line: line}, nil
+		case c == ')':
+			return pgnToken{kind: pgnClose, line: line}, nil
+		case c == '$':
+			word := l.readWord()
+			nag, err := strconv.Atoi(word)
+			if err != nil {
+				return pgnToken{}, fmt.Errorf("line %d: NAG '$%s' is malformed", line, word)
+			}
+			return pgnToken{kind: pgnNAG, nag: nag, line: line}, nil
+		default:
+			l.unreadRune(c)
+			word := l.readWord()
+			switch word {
+			case "1-0", "0-1", "1/2-1/2", "*":
+				return pgnToken{kind: pgnResult, text: word, line: line}, nil
+			}
+
+			if word = stripMoveNumber(word); word == "" {
+				continue
+			}
+
+			return pgnToken{kind: pgnMove, text: word, line: line}, nil
+		}
+	}
+}
+
+// stripMoveNumber removes a move number from a move, which may have been run
+// into it: "12.", "12..." and "1.e4" become "", "" and "e4".
+func stripMoveNumber(word string) string {
+	n := strings.IndexFunc(word, func(c rune) bool { return !unicode.IsDigit(c) })
+	switch {
+	case n == -1:
+		return ""
+	case n > 0 && word[n] == '.':
+		return strings.TrimLeft(word[n:], ".")
+	default:
+		return word
+	}
+}
+
+// readWord reads up to the next space or delimiter.
+func (l *pgnLexer) readWord() string {
+	var w strings.Builder
+	for {
+		c, err := l.readRune()
+		if err != nil {
+			return w.String()
+		}
+		if unicode.IsSpace(c) || strings.ContainsRune("{}()[];$", c) {
+			l.unreadRune(c)
+			return w.String()
+		}
+		w.WriteRune(c)
+	}
+}
+
+// readUntil reads up to and including the delimiter, returning the text before
+// it. The end of the file ends a ';' comment, anything else is an error.
+func (l *pgnLexer) readUntil(delim rune) (string, error) {
+	start := l.line
+	var s strings.Builder
+	for {
+		c, err := l.readRune()
+		if err == io.EOF && delim == '\n' {
+			return s.String(), nil
+		}
+		if err == io.EOF {
+			return "", fmt.Errorf("line %d: '%c' isn't closed", start, delim)
+		}
+		if err != nil {
+			return "", err
+		}
+		if c == delim {
+			return s.String(), nil
+		}
+		s.WriteRune(c)
+	}
+}
+
+// readTag reads up to and including the ']' closing a tag pair, which may
+// appear in the quoted value.
+func (l *pgnLexer) readTag() (string, error) {
+	start := l.line
+	var s strings.Builder
+	quoted, escaped := false, false
+	for {
+		c, err := l.readRune()
+		if err == io.EOF {
+			return "", fmt.Errorf("line %d: '[' isn't closed", start)
+		}
+		if err != nil {
+			return "", err
+		}
+		if c == ']' && !quoted {
+			return s.String(), nil
+		}
+		switch {
+		case escaped:
+			escaped = false
+		case c == '\\':
+			escaped = true
+		case c == '"':
+			quoted = !quoted
+		}
+		s.WriteRune(c)
+	}
+}
+
+// parseTag parses the inside of a tag pair, e.g. 'White "trollfish"'. Quotes
+// and backslashes in the value are escaped with a backslash.
+func parseTag(s string) (string, string, error) {
+	s = strings.TrimSpace(s)
+	i := strings.IndexFunc(s, unicode.IsSpace)
+	if i == -1 {
+		return "", "", fmt.Errorf("tag '[%s]' is malformed", s)
+	}
+
+	name, quoted := s[:i], strings.TrimSpace(s[i:])
+	if len(quoted) < 2 || quoted[0] != '"' || quoted[len(quoted)-1] != '"' {
+		return "", "", fmt.Errorf("tag '[%s]' value isn't quoted", s)
+	}
+
+	var value strings.Builder
+	escaped := false
+	for _, c := range quoted[1 : len(quoted)-1] {
+		if c == '\\' && !escaped {
+			escaped = true
+			continue
+		}
+		escaped = false
+		value.WriteRune(c)
+	}
+
+	return name, value.String(), nil
+}
@@ -0,0 +1,226 @@
+package uci
+
+import (
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testPGN = `[Event "Rated Blitz game"]
+[Site "https://lichess.org/abcd1234"]
+[White "trollfish"]
+[Black "some \"quoted\" human"]
+[Result "1-0"]
+
+{ a casual game } 1. e4 { [%eval 0.36] [%clk 0:03:00] } 1... e5 2. Qh5!? Nc6 $2
+(2... g6 3. Qf3 (3. Qxe5+ Qe7) 3... Nf6) 3. Bc4 Nf6?? 4. Qxf7# 1-0
+
+[Event "?"]
+[White "a"]
+[Black "b"]
+[Result "*"]
+
+1. d4 d5 2. Bf5 *
+
+[Event "from a FEN"]
+[SetUp "1"]
+[FEN "4k3/8/8/8/8/8/8/4K2R w K - 0 1"]
+
+1. O-O Kd7 2.Rd1+ 1/2-1/2
+`
+
+func TestPGNReader(t *testing.T) {
+	// arrange
+	r := NewPGNReader(strings.NewReader(testPGN))
+
+	// act
+	g, err := r.Next()
+
+	// assert
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := g.Tag("Black"); got != `some "quoted" human` {
+		t.Errorf("Black tag, want: 'some \"quoted\" human' got: '%s'", got)
+	}
+	if g.Result != "1-0" || g.Comment != "a casual game" {
+		t.Errorf("want result '1-0' comment 'a casual game', got '%s' '%s'", g.Result, g.Comment)
+	}
+
+	var moves, sans []string
+	for _, m := range g.Moves {
+		moves = append(moves, m.Move)
+		sans = append(sans, m.SAN)
+	}
+	if want := "e2e4 e7e5 d1h5 b8c6 f1c4 g8f6 h5f7"; strings.Join(moves, " ") != want {
+		t.Errorf("moves\nwant: %s\ngot:  %s", want, strings.Join(moves, " "))
+	}
+	if want := "e4 e5 Qh5 Nc6 Bc4 Nf6 Qxf7#"; strings.Join(sans, " ") != want {
+		t.Errorf("san\nwant: %s\ngot:  %s", want, strings.Join(sans, " "))
+	}
+	if want := []string{"[%eval 0.36] [%clk 0:03:00]"}; !reflect.DeepEqual(g.Moves[0].Comments, want) {
+		t.Errorf("comments, want: %q got: %q", want, g.Moves[0].Comments)
+	}
+	if !reflect.DeepEqual(g.Moves[2].NAGs, []int{5}) || !reflect.DeepEqual(g.Moves[3].NAGs, []int{2}) || !reflect.DeepEqual(g.Moves[5].NAGs, []int{4}) {
+		t.Errorf("NAGs, want [5] [2] [4] got: %v %v %v", g.Moves[2].NAGs, g.Moves[3].NAGs, g.Moves[5].NAGs)
+	}
+	if len(g.Moves[3].Variations) != 0 {
+		t.Errorf("variations should be skipped, got %d", len(g.Moves[3].Variations))
+	}
+
+	end := g.Replay(func(b *Board, m GameMove) {})
+	if !end.IsCheckmate() {
+		t.Errorf("want checkmate after replay, got '%s'", end.FEN())
+	}
+
+	// the second game has an illegal move, the reader carries on after it
+	_, err = r.Next()
+	if err == nil || !strings.Contains(err.Error(), "game 2 'a - b'") {
+		t.Errorf("want error for game 2, got: %v", err)
+	}
+
+	g, err = r.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := g.Moves[0].Move + " " + g.Moves[2].Move; got != "e1g1 f1d1" || g.Result != "1/2-1/2" {
+		t.Errorf("want e1g1 f1d1 1/2-1/2, got %s %s", got, g.Result)
+	}
+
+	if _, err = r.Next(); err != io.EOF {
+		t.Errorf("want io.EOF, got: %v", err)
+	}
+}
+
+func TestPGNReaderVariations(t *testing.T) {
+	// arrange
+	r := NewPGNReader(strings.NewReader(testPGN))
+	r.KeepVariations = true
+
+	// act
+	g, err := r.Next()
+
+	// assert
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	variations := g.Moves[3].Variations
+	if len(variations) != 1 {
+		t.Fatalf("want 1 variation on Nc6, got %d", len(variations))
+	}
+
+	var moves []string
+	for _, m := range variations[0] {
+		moves = append(moves, m.Move)
+	}
+	if want := "g7g6 h5f3 g8f6"; strings.Join(moves, " ") != want {
+		t.Errorf("variation\nwant: %s\ngot:  %s", want, strings.Join(moves, " "))
+	}
+
+	nested := variations[0][1].Variations
+	if len(nested) != 1 || nested[0][0].Move != "h5e5" || nested[0][1].Move != "d8e7" {
+		t.Errorf("want nested variation Qxe5+ Qe7, got %v", nested)
+	}
+}
+
+func TestPGNReaderErrors(t *testing.T) {
+	// arrange
+	cases := []struct {
+		name     string
+		pgn      string
+		expError string
+	}{
+		{name: "illegal move", pgn: "1. e4 e5 2. Ke3 *", expError: "game 1: line 1:"},
+		{name: "bad FEN", pgn: "[FEN \"8/8 w - - 0 1\"]\n\n1. e4 *", expError: "invalid FEN"},
+		{name: "unclosed comment", pgn: "1. e4 { oops", expError: "'}' isn't closed"},
+		{name: "unclosed variation", pgn: "1. e4 (1. d4 *", expError: "variation isn't closed"},
+		{name: "stray close", pgn: "1. e4 ) *", expError: "unexpected ')'"},
+		{name: "bad NAG", pgn: "1. e4 $x *", expError: "NAG '$x' is malformed"},
+		{name: "unquoted tag", pgn: "[White trollfish]\n\n*", expError: "value isn't quoted"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			// act
+			_, err := ReadPGN(strings.NewReader(c.pgn))
+
+			// assert
+			if err == nil || !strings.Contains(err.Error(), c.expError) {
+				t.Errorf("want error containing '%s', got: %v", c.expError, err)
+			}
+		})
+	}
+}
+
+// errReader fails every read.
+type errReader struct{}
+
+func (errReader) Read([]byte) (int, error) {
+	return 0, errors.New("read failed")
+}
+
+func TestPGNReaderReadError(t *testing.T) {
+	// arrange
+	r := NewPGNReader(io.MultiReader(strings.NewReader("1. e4 e5 *\n"), errReader{}))
+
+	// act
+	g, err := r.Next()
+
+	// assert
+	if err != nil || len(g.Moves) != 2 {
+		t.Fatalf("want the first game got: %v %v", g, err)
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := r.Next(); err == nil || err.Error() != "read failed" {
+			t.Errorf("want error 'read failed' got: %v", err)
+		}
+	}
+	if err := r.Err(); err == nil {
+		t.Errorf("want Err to return the read error")
+	}
+}
+
+func TestWritePGN(t *testing.T) {
+	// arrange
+	r := NewPGNReader(strings.NewReader(testPGN))
+	r.KeepVariations = true
+	g, err := r.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `[Event "Rated Blitz game"]
+[Site "https://lichess.org/abcd1234"]
+[White "trollfish"]
+[Black "some \"quoted\" human"]
+[Result "1-0"]
+
+{ a casual game } 1. e4 { [%eval 0.36] [%clk 0:03:00] } 1... e5 2. Qh5 $5 Nc6 $2
+(2... g6 3. Qf3 (3. Qxe5+ Qe7) 3... Nf6) 3. Bc4 Nf6 $4 4. Qxf7# 1-0
+
+`
+
+	// act
+	var sb strings.Builder
+	err = g.WritePGN(&sb)
+
+	// assert
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sb.String() != want {
+		t.Errorf("\nwant:\n%s\ngot:\n%s", want, sb.String())
+	}
+
+	// the written game reads back the same
+	g2, err := NewPGNReader(strings.NewReader(sb.String())).Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(g.Tags, g2.Tags) || len(g.Moves) != len(g2.Moves) {
+		t.Errorf("round trip differs")
+	}
+}
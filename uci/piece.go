@@ -0,0 +1,83 @@
+package uci
+
+import "strings"
+
+// PieceType is a kind of piece regardless of its color.
+type PieceType uint8
+
+const (
+	NoPieceType PieceType = iota
+	Pawn
+	Knight
+	Bishop
+	Rook
+	Queen
+	King
+)
+
+// Piece is a piece of a given color.
+type Piece uint8
+
+const (
+	NoPiece Piece = iota
+	WhitePawn
+	WhiteKnight
+	WhiteBishop
+	WhiteRook
+	WhiteQueen
+	WhiteKing
+	BlackPawn
+	BlackKnight
+	BlackBishop
+	BlackRook
+	BlackQueen
+	BlackKing
+)
+
+// pieceRunes is indexed by Piece.
+const pieceRunes = " PNBRQKpnbrqk"
+
+func makePiece(c Color, t PieceType) Piece {
+	return Piece(uint8(c)*6 + uint8(t))
+}
+
+// pieceFromRune returns the piece for its FEN letter, or NoPiece.
+func pieceFromRune(c rune) Piece {
+	i := strings.IndexRune(pieceRunes[1:], c)
+	if i == -1 {
+		return NoPiece
+	}
+	return Piece(i + 1)
+}
+
+func (p Piece) Type() PieceType {
+	if p == NoPiece {
+		return NoPieceType
+	}
+	return PieceType((p-1)%6 + 1)
+}
+
+func (p Piece) Color() Color {
+	if p > WhiteKing {
+		return Black
+	}
+	return White
+}
+
+// String returns the piece's FEN letter, or " " for NoPiece.
+func (p Piece) String() string {
+	return string(p.rune())
+}
+
+func (p Piece) rune() rune {
+	return rune(pieceRunes[p])
+}
+
+// String returns the piece type's lowercase letter, as used for promotions in
+// UCI notation.
+func (t PieceType) String() string {
+	if t == NoPieceType {
+		return ""
+	}
+	return string(pieceRunes[BlackPawn-1+Piece(t)])
+}
@@ -0,0 +1,209 @@
+package uci
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"sort"
+)
+
+// polyglotEntrySize is the size of an entry in a Polyglot .bin book: key,
+// move, weight and learn, big-endian.
+const polyglotEntrySize = 16
+
+// PolyglotBook is an opening book in the Polyglot .bin format, entries sorted
+// by position key (see Board.Key).
+type PolyglotBook struct {
+	Entries []PolyglotEntry
+}
+
+// PolyglotEntry is a book move. Move is in Polyglot's encoding, where
+// castling is the king taking its rook; see PolyglotBook.Moves.
+type PolyglotEntry struct {
+	Key    uint64
+	Move   uint16
+	Weight uint16
+	Learn  uint32
+}
+
+// BookMove is a legal move from a book with its weight.
+type BookMove struct {
+	Move   string
+	Weight int
+}
+
+// polyglotPromotions maps Polyglot's promotion piece to ours.
+var polyglotPromotions = [5]PieceType{NoPieceType, Knight, Bishop, Rook, Queen}
+
+// LoadPolyglotBook reads a Polyglot book from a file.
+func LoadPolyglotBook(path string) (*PolyglotBook, error) {
+	fp, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer fp.Close()
+
+	book, err := ReadPolyglotBook(fp)
+	if err != nil {
+		return nil, fmt.Errorf("book '%s': %v", path, err)
+	}
+	return book, nil
+}
+
+// ReadPolyglotBook reads a Polyglot book. Entries out of key order are sorted
+// so lookups work with books from any tool.
+func ReadPolyglotBook(r io.Reader) (*PolyglotBook, error) {
+	var book PolyglotBook
+
+	br := bufio.NewReader(r)
+	var buf [polyglotEntrySize]byte
+	for {
+		if _, err := io.ReadFull(br, buf[:]); err == io.EOF {
+			break
+		} else if err == io.ErrUnexpectedEOF {
+			return nil, fmt.Errorf("entry %d is truncated", len(book.Entries))
+		} else if err != nil {
+			return nil, err
+		}
+
+		book.Entries = append(book.Entries, PolyglotEntry{
+			Key:    binary.BigEndian.Uint64(buf[0:8]),
+			Move:   binary.BigEndian.Uint16(buf[8:10]),
+			Weight: binary.BigEndian.Uint16(buf[10:12]),
+			Learn:  binary.BigEndian.Uint32(buf[12:16]),
+		})
+	}
+
+	if !sort.SliceIsSorted(book.Entries, book.less) {
+		sort.SliceStable(book.Entries, book.less)
+	}
+
+	return &book, nil
+}
+
+func (pb *PolyglotBook) less(i, j int) bool {
+	return pb.Entries[i].Key < pb.Entries[j].Key
+}
+
+// entries returns the entries for the position key.
+func (pb *PolyglotBook) entries(key uint64) []PolyglotEntry {
+	i := sort.Search(len(pb.Entries), func(i int) bool { return pb.Entries[i].Key >= key })
+	j := i
+	for j < len(pb.Entries) && pb.Entries[j].Key == key {
+		j++
+	}
+	return pb.Entries[i:j]
+}
+
+// Moves returns the book moves for the position in UCI notation. Moves which
+// aren't legal, for example because of a key collision, are left out.
+func (pb *PolyglotBook) Moves(b *Board) []BookMove {
+	var moves []BookMove
+	legal := b.GenerateMoves()
+	for _, e := range pb.entries(b.Key()) {
+		m := b.polyglotMove(e.Move)
+		if !containsMove(legal, m) {
+			continue
+		}
+		moves = append(moves, BookMove{Move: m.String(), Weight: int(e.Weight)})
+	}
+	return moves
+}
+
+// Pick returns a book move for the position chosen at random in proportion to
+// its weight, or "" if the position isn't in the book. Moves with a weight of
+// 0 are never picked.
+func (pb *PolyglotBook) Pick(b *Board) string {
+	return pickWeighted(pb.Moves(b))
+}
+
+// pickWeighted returns a move chosen at random in proportion to its weight, or
+// "" if no move has a weight.
+func pickWeighted(moves []BookMove) string {
+	var total int
+	for _, m := range moves {
+		total += m.Weight
+	}
+	if total == 0 {
+		return ""
+	}
+
+	n := rand.Intn(total)
+	for _, m := range moves {
+		if n < m.Weight {
+			return m.Move
+		}
+		n -= m.Weight
+	}
+	return ""
+}
+
+// polyglotMove decodes a book move. Polyglot writes castling as the king
+// taking its own rook, which is how we write it in Chess960; otherwise it
+// becomes the king's two-square move.
+func (b *Board) polyglotMove(raw uint16) Move {
+	to := makeSquare(int(raw&7), int(raw>>3&7))
+	from := makeSquare(int(raw>>6&7), int(raw>>9&7))
+
+	var promotion PieceType
+	if p := int(raw >> 12 & 7); p < len(polyglotPromotions) {
+		promotion = polyglotPromotions[p]
+	}
+
+	if !b.Chess960 && b.squares[from].Type() == King && b.squares[to] == makePiece(b.squares[from].Color(), Rook) {
+		if to.File() > from.File() {
+			to = makeSquare(6, to.Rank())
+		} else {
+			to = makeSquare(2, to.Rank())
+		}
+	}
+
+	return NewMove(from, to, promotion)
+}
+
+// Write writes the book in the Polyglot .bin format, sorted by key with the
+// heaviest move first.
+func (pb *PolyglotBook) Write(w io.Writer) error {
+	entries := make([]PolyglotEntry, len(pb.Entries))
+	copy(entries, pb.Entries)
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].Key != entries[j].Key {
+			return entries[i].Key < entries[j].Key
+		}
+		return entries[i].Weight > entries[j].Weight
+	})
+
+	bw := bufio.NewWriter(w)
+	var buf [polyglotEntrySize]byte
+	for _, e := range entries {
+		binary.BigEndian.PutUint64(buf[0:8], e.Key)
+		binary.BigEndian.PutUint16(buf[8:10], e.Move)
+		binary.BigEndian.PutUint16(buf[10:12], e.Weight)
+		binary.BigEndian.PutUint32(buf[12:16], e.Learn)
+		if _, err := bw.Write(buf[:]); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// encodePolyglotMove returns the move in Polyglot's encoding, the reverse of
+// polyglotMove.
+func (b *Board) encodePolyglotMove(m Move) uint16 {
+	from, to := m.From(), m.To()
+	if side := b.castlingSide(from, to, b.castling); side != -1 {
+		to = makeSquare(b.castling[side], castlingRank(side))
+	}
+
+	var promotion uint16
+	for i, p := range polyglotPromotions {
+		if p == m.Promotion() && p != NoPieceType {
+			promotion = uint16(i)
+		}
+	}
+
+	return uint16(to.File()) | uint16(to.Rank())<<3 | uint16(from.File())<<6 | uint16(from.Rank())<<9 | promotion<<12
+}
@@ -0,0 +1,116 @@
+package uci
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+// testPolyglotMove encodes a UCI move the way Polyglot does.
+func testPolyglotMove(move string, promotion uint16) uint16 {
+	m, err := ParseMove(move[:4])
+	if err != nil {
+		panic(err)
+	}
+	from, to := m.From(), m.To()
+	return uint16(to.File()) | uint16(to.Rank())<<3 | uint16(from.File())<<6 | uint16(from.Rank())<<9 | promotion<<12
+}
+
+func testPolyglotBook(entries ...PolyglotEntry) []byte {
+	var buf bytes.Buffer
+	for _, e := range entries {
+		_ = binary.Write(&buf, binary.BigEndian, e)
+	}
+	return buf.Bytes()
+}
+
+func TestPolyglotBookMoves(t *testing.T) {
+	castling := FENtoBoard("r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1")
+	promotion := FENtoBoard("8/1P2k3/8/8/8/8/8/4K3 w - - 0 1")
+
+	// out of order on purpose, and an illegal move from a key collision
+	data := testPolyglotBook(
+		PolyglotEntry{Key: 0x463b96181691fc9c, Move: testPolyglotMove("d2d4", 0), Weight: 1},
+		PolyglotEntry{Key: castling.Key(), Move: testPolyglotMove("e1h1", 0), Weight: 5},
+		PolyglotEntry{Key: castling.Key(), Move: testPolyglotMove("e1a1", 0), Weight: 2},
+		PolyglotEntry{Key: 0x463b96181691fc9c, Move: testPolyglotMove("e2e4", 0), Weight: 3},
+		PolyglotEntry{Key: 0x463b96181691fc9c, Move: testPolyglotMove("e2e5", 0), Weight: 9},
+		PolyglotEntry{Key: 0x823c9b50fd114196, Move: testPolyglotMove("c7c5", 0), Weight: 0},
+		PolyglotEntry{Key: promotion.Key(), Move: testPolyglotMove("b7b8", 1), Weight: 1},
+	)
+
+	book, err := ReadPolyglotBook(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	chess960 := castling
+	chess960.Chess960 = true
+
+	// arrange
+	cases := []struct {
+		name string
+		b    Board
+		want []BookMove
+	}{
+		{name: "start position", b: FENtoBoard(startPosFEN), want: []BookMove{{Move: "d2d4", Weight: 1}, {Move: "e2e4", Weight: 3}}},
+		{name: "castling", b: castling, want: []BookMove{{Move: "e1g1", Weight: 5}, {Move: "e1c1", Weight: 2}}},
+		{name: "castling in chess960", b: chess960, want: []BookMove{{Move: "e1h1", Weight: 5}, {Move: "e1a1", Weight: 2}}},
+		{name: "underpromotion", b: promotion, want: []BookMove{{Move: "b7b8n", Weight: 1}}},
+		{name: "not in book", b: FENtoBoard("4k3/8/8/8/8/8/8/4K3 w - - 0 1"), want: nil},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			// act
+			got := book.Moves(&c.b)
+
+			// assert
+			if !reflect.DeepEqual(got, c.want) {
+				t.Errorf("want: %v got: %v", c.want, got)
+			}
+		})
+	}
+
+	// weight 0 is never played
+	after := FENtoBoard(startPosFEN)
+	after.Moves("e2e4")
+	if move := book.Pick(&after); move != "" {
+		t.Errorf("want no move with weight 0, got %s", move)
+	}
+}
+
+func TestPickWeighted(t *testing.T) {
+	// arrange
+	rand.Seed(1)
+	moves := []BookMove{{Move: "e2e4", Weight: 3}, {Move: "d2d4", Weight: 1}, {Move: "g1f3", Weight: 0}}
+	counts := make(map[string]int)
+
+	// act
+	for i := 0; i < 4000; i++ {
+		counts[pickWeighted(moves)]++
+	}
+
+	// assert
+	if counts["g1f3"] != 0 || counts[""] != 0 {
+		t.Errorf("want only e2e4 and d2d4, got %v", counts)
+	}
+	if n := counts["e2e4"]; n < 2800 || n > 3200 {
+		t.Errorf("want e2e4 about 3000 times, got %d", n)
+	}
+}
+
+func TestReadPolyglotBookTruncated(t *testing.T) {
+	// arrange
+	data := testPolyglotBook(PolyglotEntry{Key: 1, Move: 2, Weight: 3}, PolyglotEntry{Key: 2, Move: 2, Weight: 3})
+
+	// act
+	_, err := ReadPolyglotBook(bytes.NewReader(data[:20]))
+
+	// assert
+	if err == nil || err.Error() != "entry 1 is truncated" {
+		t.Errorf("want 'entry 1 is truncated', got: %v", err)
+	}
+}
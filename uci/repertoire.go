@@ -0,0 +1,210 @@
+package uci
+
+import (
+	"bufio"
+	_ "embed"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// defaultRepertoire is the repertoire played unless the Repertoire option
+// names another file. See repertoire.txt for the format.
+//
+//go:embed repertoire.txt
+var defaultRepertoire string
+
+// repertoireAny is the section of lines played against every opponent.
+const repertoireAny = "any"
+
+// Repertoire is the troll's book of opening lines, read from a text file of
+// 'play' and 'answer' lines; see repertoire.txt. Positions are looked up by
+// their Polyglot key, like the BookFile option's book and book learning, so
+// lines which transpose share their moves.
+type Repertoire struct {
+	// sections holds the moves by position key for each section: lines for
+	// any opponent, or only for humans or computers
+	sections map[string]map[uint64][]BookMove
+}
+
+// LoadRepertoire reads a repertoire file.
+func LoadRepertoire(path string) (*Repertoire, error) {
+	fp, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer fp.Close()
+
+	rep, err := ParseRepertoire(fp)
+	if err != nil {
+		return nil, fmt.Errorf("repertoire '%s': %v", path, err)
+	}
+	return rep, nil
+}
+
+// ParseRepertoire reads a repertoire. Every move is checked for legality, so
+// a line which can't be played is an error rather than a book move which is
+// silently never found.
+func ParseRepertoire(r io.Reader) (*Repertoire, error) {
+	rep := Repertoire{sections: make(map[string]map[uint64][]BookMove)}
+
+	section := repertoireAny
+	s := bufio.NewScanner(r)
+	for n := 1; s.Scan(); n++ {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if fields := strings.Fields(line); fields[0] == "section" {
+			if len(fields) != 2 || (fields[1] != repertoireAny && fields[1] != opponentHuman && fields[1] != opponentComputer) {
+				return nil, fmt.Errorf("line %d: '%s' should be 'section any', 'section human' or 'section computer'", n, line)
+			}
+			section = fields[1]
+			continue
+		}
+
+		if err := rep.addLine(section, line); err != nil {
+			return nil, fmt.Errorf("line %d: %v", n, err)
+		}
+	}
+
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+
+	return &rep, nil
+}
+
+// mustParseRepertoire parses a repertoire which is known to be valid.
+func mustParseRepertoire(s string) *Repertoire {
+	rep, err := ParseRepertoire(strings.NewReader(s))
+	if err != nil {
+		panic(err)
+	}
+	return rep
+}
+
+func (rep *Repertoire) addLine(section, line string) error {
+	fields := strings.Fields(line)
+	kind := fields[0]
+	if kind != "play" && kind != "answer" {
+		return fmt.Errorf("'%s' should start with 'play' or 'answer'", line)
+	}
+
+	// ours is the index of the first move we play
+	ours := -1
+	var moves []string
+	for _, tok := range fields[1:] {
+		if tok == "|" {
+			if kind != "play" || ours != -1 {
+				return fmt.Errorf("'%s' can only have one '|', in a 'play' line", line)
+			}
+			ours = len(moves)
+			continue
+		}
+		if move := stripMoveNumber(tok); move != "" {
+			moves = append(moves, move)
+		}
+	}
+	if len(moves) == 0 {
+		return fmt.Errorf("'%s' has no moves", line)
+	}
+
+	if kind == "answer" {
+		ours = len(moves) - 1
+	} else if ours == -1 {
+		ours = 0
+	}
+
+	b := FENtoBoard(startPosFEN)
+	for i, move := range moves {
+		san, weight := move, 1
+		if j := strings.IndexByte(move, ':'); j != -1 {
+			var err error
+			san = move[:j]
+			if weight, err = strconv.Atoi(move[j+1:]); err != nil || weight < 0 {
+				return fmt.Errorf("move '%s' has an invalid weight", move)
+			}
+			if i < ours {
+				return fmt.Errorf("move '%s' is weighted but we don't play it", move)
+			}
+		}
+
+		uciMove, err := b.ParseSAN(san)
+		if err != nil {
+			return err
+		}
+
+		if i >= ours {
+			rep.add(section, &b, uciMove, weight)
+		}
+		b.Moves(uciMove)
+	}
+
+	return nil
+}
+
+// add adds the move to the position in the section. A move already in the
+// section keeps the highest weight it's given.
+func (rep *Repertoire) add(section string, b *Board, move string, weight int) {
+	positions := rep.sections[section]
+	if positions == nil {
+		positions = make(map[uint64][]BookMove)
+		rep.sections[section] = positions
+	}
+
+	key := b.Key()
+	positions[key] = addBookMove(positions[key], move, weight)
+}
+
+// addBookMove adds the move to moves, or raises its weight if it's already
+// there.
+func addBookMove(moves []BookMove, move string, weight int) []BookMove {
+	for i, m := range moves {
+		if m.Move == move {
+			moves[i].Weight = max(m.Weight, weight)
+			return moves
+		}
+	}
+	return append(moves, BookMove{Move: move, Weight: weight})
+}
+
+// Moves returns the repertoire moves for the position against an opponent we
+// know nothing about, only the lines for any opponent.
+func (rep *Repertoire) Moves(b *Board) []BookMove {
+	return rep.MovesFor(b, Opponent{})
+}
+
+// MovesFor returns the repertoire moves for the position against the
+// opponent: the lines of its kind's section, if it's known, then the lines for
+// any opponent. A move in both keeps its highest weight.
+func (rep *Repertoire) MovesFor(b *Board, o Opponent) []BookMove {
+	key := b.Key()
+
+	var moves []BookMove
+	for _, section := range []string{o.Kind, repertoireAny} {
+		for _, m := range rep.sections[section][key] {
+			moves = addBookMove(moves, m.Move, m.Weight)
+		}
+	}
+	return moves
+}
+
+// Pick returns a repertoire move for the position chosen at random in
+// proportion to its weight, or "" if the position isn't in the repertoire.
+func (rep *Repertoire) Pick(b *Board) string {
+	return pickWeighted(rep.Moves(b))
+}
+
+// size returns how many positions the repertoire has moves for, counting a
+// position in two sections twice.
+func (rep *Repertoire) size() int {
+	var n int
+	for _, positions := range rep.sections {
+		n += len(positions)
+	}
+	return n
+}
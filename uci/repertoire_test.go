@@ -0,0 +1,131 @@
+package uci
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDefaultRepertoire(t *testing.T) {
+	rep := mustParseRepertoire(defaultRepertoire)
+	human := Opponent{Kind: opponentHuman}
+
+	// arrange
+	cases := []struct {
+		name  string
+		moves string
+		want  []BookMove
+	}{
+		{name: "start position is left to the first move table", moves: "", want: nil},
+		{name: "Wayward Queen", moves: "e2e4 e7e5", want: []BookMove{{Move: "d1h5", Weight: 1}}},
+		{name: "Englund Gambit, 1... Nf6 kept but not played", moves: "d2d4", want: []BookMove{{Move: "e7e5", Weight: 1}, {Move: "g8f6", Weight: 0}}},
+		{name: "Englund Gambit 2. dxe5", moves: "d2d4 e7e5", want: []BookMove{{Move: "d4e5", Weight: 1}}},
+		{name: "Englund Gambit 3. Bf4 Qe7", moves: "d2d4 e7e5 d4e5 b8c6 c1f4", want: []BookMove{{Move: "d8e7", Weight: 1}}},
+		{name: "Englund Gambit transposes", moves: "d2d4 e7e5 d4e5 b8c6 c1f4 d8e7 g1f3", want: []BookMove{{Move: "e7b4", Weight: 1}}},
+		{name: "Englund Gambit 5. Bd2 Qxb2", moves: "d2d4 e7e5 d4e5 b8c6 g1f3 d8e7 c1g5 e7b4 g5d2", want: []BookMove{{Move: "b4b2", Weight: 1}}},
+		{name: "Englund Gambit 6. Nc3 Bb4", moves: "d2d4 e7e5 d4e5 b8c6 g1f3 d8e7 c1g5 e7b4 g5d2 b4b2 b1c3", want: []BookMove{{Move: "f8b4", Weight: 1}}},
+		{name: "Smith-Morra 1... c5", moves: "e2e4", want: []BookMove{{Move: "c7c5", Weight: 1}}},
+		{name: "Smith-Morra 5... Nc6 is reachable", moves: "e2e4 c7c5 d2d4 c5d4 c2c3 d4c3 b1c3 d7d6 f1c4", want: []BookMove{{Move: "b8c6", Weight: 1}}},
+		{name: "Smith-Morra answers 5... e6", moves: "e2e4 c7c5 d2d4 c5d4 c2c3 d4c3 b1c3 d7d6 f1c4 e7e6", want: []BookMove{{Move: "g1f3", Weight: 1}}},
+		{name: "Smith-Morra doesn't play 4... d6 itself", moves: "e2e4 c7c5 d2d4 c5d4 c2c3 d4c3 b1c3", want: nil},
+		{name: "Reverse Morra 1... d5", moves: "c2c4", want: []BookMove{{Move: "d7d5", Weight: 1}}},
+		{name: "Reverse Morra 4... a6", moves: "c2c4 d7d5 c4d5 c7c6 d5c6 b8c6 b1c3", want: []BookMove{{Move: "a7a6", Weight: 1}}},
+		{name: "d4 Opening 2. c4", moves: "d2d4 g8f6", want: []BookMove{{Move: "c2c4", Weight: 1}}},
+		{name: "d4 Opening 3... b6 by transposition", moves: "d2d4 g8f6 c2c4 e7e6 g1f3", want: []BookMove{{Move: "b7b6", Weight: 1}}},
+		{name: "d4 Opening 6... Nbd7", moves: "d2d4 g8f6 g1f3 e7e6 c2c4 b7b6 g2g3 c8a6 b2b3 d7d5 f1g2", want: []BookMove{{Move: "b8d7", Weight: 1}}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			b := FENtoBoard(startPosFEN)
+			b.Moves(strings.Fields(c.moves)...)
+
+			// act
+			got := rep.MovesFor(&b, human)
+
+			// assert
+			if !reflect.DeepEqual(got, c.want) {
+				t.Errorf("want: %v got: %v", c.want, got)
+			}
+		})
+	}
+}
+
+func TestParseRepertoire(t *testing.T) {
+	// arrange
+	cases := []struct {
+		name     string
+		text     string
+		moves    string
+		want     []BookMove
+		expError string
+	}{
+		{name: "weighted replies", text: "play e4 | e5:3\nplay e4 | c5\nanswer e4 e6 d4", moves: "e2e4", want: []BookMove{{Move: "e7e5", Weight: 3}, {Move: "c7c5", Weight: 1}}},
+		{name: "highest weight wins", text: "play 1.e4 e5:3\nplay 1. e4 e5", moves: "e2e4", want: []BookMove{{Move: "e7e5", Weight: 3}}},
+		{name: "answer only adds the reply", text: "# comment\n\nanswer 1. e4 e6 2. d4", moves: "e2e4", want: nil},
+		{name: "play adds every move", text: "play 1. e4 e6 2. d4", moves: "", want: []BookMove{{Move: "e2e4", Weight: 1}}},
+		{name: "illegal move", text: "play e4 e5\nplay e4 Ke3", expError: "line 2: "},
+		{name: "unknown kind", text: "plya e4", expError: "line 1: 'plya e4' should start with 'play' or 'answer'"},
+		{name: "'|' in an answer line", text: "answer e4 | e5", expError: "can only have one '|'"},
+		{name: "two '|'", text: "play e4 | e5 | Nf3", expError: "can only have one '|'"},
+		{name: "weight on a move we don't play", text: "answer e4:2 e5", expError: "move 'e4:2' is weighted but we don't play it"},
+		{name: "bad weight", text: "play e4:x", expError: "move 'e4:x' has an invalid weight"},
+		{name: "no moves", text: "play 1.", expError: "has no moves"},
+		{name: "unknown section", text: "section bots\nplay e4", expError: "line 1: 'section bots' should be 'section any', 'section human' or 'section computer'"},
+		{name: "section", text: "section computer\nplay e4 | e5:2\nsection any\nplay e4 | e5", moves: "e2e4", want: []BookMove{{Move: "e7e5", Weight: 1}}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			// act
+			rep, err := ParseRepertoire(strings.NewReader(c.text))
+
+			// assert
+			if c.expError != "" {
+				if err == nil || !strings.Contains(err.Error(), c.expError) {
+					t.Fatalf("want error containing '%s', got: %v", c.expError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			b := FENtoBoard(startPosFEN)
+			b.Moves(strings.Fields(c.moves)...)
+			if got := rep.Moves(&b); !reflect.DeepEqual(got, c.want) {
+				t.Errorf("want: %v got: %v", c.want, got)
+			}
+		})
+	}
+}
+
+func TestRepertoireTranspositions(t *testing.T) {
+	rep := mustParseRepertoire("play 1. e4 e6 2. d4 d5 3. Nc3 | Nf6:2\nplay 1. c4 e5 | Nc3")
+
+	// arrange
+	cases := []struct {
+		name string
+		fen  string
+		want []BookMove
+	}{
+		{name: "by the book", fen: "rnbqkbnr/ppp2ppp/4p3/3p4/3PP3/2N5/PPP2PPP/R1BQKBNR b KQkq - 1 3", want: []BookMove{{Move: "g8f6", Weight: 2}}},
+		{name: "move counters", fen: "rnbqkbnr/ppp2ppp/4p3/3p4/3PP3/2N5/PPP2PPP/R1BQKBNR b KQkq - 5 9", want: []BookMove{{Move: "g8f6", Weight: 2}}},
+		{name: "en passant square which can't be taken", fen: "rnbqkbnr/pppp1ppp/8/4p3/2P5/8/PP1PPPPP/RNBQKBNR w KQkq e6 0 2", want: []BookMove{{Move: "b1c3", Weight: 1}}},
+		{name: "castling rights differ", fen: "rnbqkbnr/ppp2ppp/4p3/3p4/3PP3/2N5/PPP2PPP/R1BQKBNR b Qkq - 1 3", want: nil},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			b := FENtoBoard(c.fen)
+
+			// act
+			got := rep.Moves(&b)
+
+			// assert
+			if !reflect.DeepEqual(got, c.want) {
+				t.Errorf("want: %v got: %v", c.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,232 @@
+package uci
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SAN returns the UCI move in Standard Algebraic Notation, e.g. "f3e5" -> "Nxe5+".
+func (b *Board) SAN(move string) (string, error) {
+	m, err := ParseMove(move)
+	legal := b.GenerateMoves()
+	if err != nil || !containsMove(legal, m) {
+		return "", fmt.Errorf("move '%s' is not legal in '%s'", move, b.FEN())
+	}
+
+	var san strings.Builder
+
+	from, to := m.From(), m.To()
+	piece := b.squares[from].Type()
+	isCapture := b.squares[to] != NoPiece || (piece == Pawn && to == b.EnPassantSquare)
+
+	castle := b.castlingSide(from, to, b.castling)
+
+	switch {
+	case castle != -1 && castle%2 == 0:
+		san.WriteString("O-O")
+	case castle != -1:
+		san.WriteString("O-O-O")
+	case piece == Pawn:
+		if isCapture {
+			san.WriteByte(move[0])
+			san.WriteByte('x')
+		}
+		san.WriteString(move[2:4])
+		if p := m.Promotion(); p != NoPieceType {
+			san.WriteByte('=')
+			san.WriteString(strings.ToUpper(p.String()))
+		}
+	default:
+		san.WriteString(strings.ToUpper(piece.String()))
+		san.WriteString(b.disambiguate(m, legal))
+		if isCapture {
+			san.WriteByte('x')
+		}
+		san.WriteString(move[2:4])
+	}
+
+	c := b.clone()
+	c.MakeMove(m)
+	if c.InCheck() {
+		if len(c.GenerateMoves()) == 0 {
+			san.WriteByte('#')
+		} else {
+			san.WriteByte('+')
+		}
+	}
+
+	return san.String(), nil
+}
+
+// SANLine converts a sequence of UCI moves played from this position to SAN.
+// The board isn't modified.
+func (b *Board) SANLine(moves ...string) ([]string, error) {
+	c := b.clone()
+	line := make([]string, 0, len(moves))
+	for _, move := range moves {
+		san, err := c.SAN(move)
+		if err != nil {
+			return line, err
+		}
+		line = append(line, san)
+		c.Moves(move)
+	}
+	return line, nil
+}
+
+// sanOrUCI returns the move in SAN if it's legal, otherwise the move unchanged.
+// It's meant for log lines.
+func (b *Board) sanOrUCI(move string) string {
+	if b.empty() {
+		return move
+	}
+	san, err := b.SAN(move)
+	if err != nil {
+		return move
+	}
+	return san
+}
+
+// ParseSAN returns the UCI move for a move in Standard Algebraic Notation,
+// e.g. "Nxe5+" -> "f3e5". It accepts common variations such as a missing
+// capture or promotion marker, "0-0" for castling and extra disambiguation.
+func (b *Board) ParseSAN(san string) (string, error) {
+	s := strings.TrimRight(strings.TrimSpace(san), "+#!?")
+	if s == "" {
+		return "", fmt.Errorf("move '%s' is empty", san)
+	}
+
+	legal := b.GenerateMoves()
+
+	var matches []Move
+	switch strings.ReplaceAll(s, "0", "O") {
+	case "O-O", "O-O-O":
+		queenSide := len(s) == 5
+		for _, m := range legal {
+			castle := b.castlingSide(m.From(), m.To(), b.castling)
+			if castle != -1 && (castle%2 == 1) == queenSide {
+				matches = append(matches, m)
+			}
+		}
+	default:
+		var err error
+		matches, err = b.matchSAN(s, legal)
+		if err != nil {
+			return "", fmt.Errorf("move '%s' %v", san, err)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return "", fmt.Errorf("move '%s' is not legal in '%s'", san, b.FEN())
+	case 1:
+		return matches[0].String(), nil
+	default:
+		ambiguous := make([]string, len(matches))
+		for i, m := range matches {
+			ambiguous[i] = m.String()
+		}
+		return "", fmt.Errorf("move '%s' is ambiguous in '%s': %s", san, b.FEN(), strings.Join(ambiguous, " "))
+	}
+}
+
+func (b *Board) matchSAN(s string, legal []Move) ([]Move, error) {
+	piece := Pawn
+	if i := strings.IndexByte("NBRQK", s[0]); i != -1 {
+		piece = Knight + PieceType(i)
+		s = s[1:]
+	}
+
+	var promote string
+	if i := strings.IndexByte(s, '='); i != -1 {
+		promote, s = s[i+1:], s[:i]
+	} else if n := len(s); piece == Pawn && n > 2 && strings.ContainsRune("QRBN", rune(s[n-1])) {
+		promote, s = s[n-1:], s[:n-1]
+	}
+	promote = strings.ToLower(promote)
+	if len(promote) > 1 || (promote != "" && !strings.Contains("qrbn", promote)) {
+		return nil, fmt.Errorf("has unknown promotion '%s'", promote)
+	}
+
+	s = strings.NewReplacer("x", "", "-", "", ":", "").Replace(s)
+	if len(s) < 2 || len(s) > 4 {
+		return nil, fmt.Errorf("is malformed")
+	}
+
+	to, hint := s[len(s)-2:], s[:len(s)-2]
+	if !isSquare(to) {
+		return nil, fmt.Errorf("has invalid square '%s'", to)
+	}
+
+	var matches []Move
+	for _, m := range legal {
+		if m.To().String() != to || m.Promotion().String() != promote {
+			continue
+		}
+		if b.squares[m.From()].Type() != piece {
+			continue
+		}
+		if !matchesHint(m.From().String(), hint) {
+			continue
+		}
+		matches = append(matches, m)
+	}
+
+	return matches, nil
+}
+
+// disambiguate returns the file, rank or square needed to tell the move apart
+// from other legal moves of the same kind of piece to the same square.
+func (b *Board) disambiguate(m Move, legal []Move) string {
+	from := m.From()
+	piece := b.squares[from]
+
+	sameFile, sameRank, others := false, false, false
+	for _, other := range legal {
+		if other == m || other.To() != m.To() || b.squares[other.From()] != piece {
+			continue
+		}
+		others = true
+		if other.From().File() == from.File() {
+			sameFile = true
+		}
+		if other.From().Rank() == from.Rank() {
+			sameRank = true
+		}
+	}
+
+	square := from.String()
+	switch {
+	case !others:
+		return ""
+	case !sameFile:
+		return square[:1]
+	case !sameRank:
+		return square[1:]
+	default:
+		return square
+	}
+}
+
+func matchesHint(square, hint string) bool {
+	for i := 0; i < len(hint); i++ {
+		c := hint[i]
+		switch {
+		case c >= 'a' && c <= 'h':
+			if square[0] != c {
+				return false
+			}
+		case c >= '1' && c <= '8':
+			if square[1] != c {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func isSquare(s string) bool {
+	return len(s) == 2 && s[0] >= 'a' && s[0] <= 'h' && s[1] >= '1' && s[1] <= '8'
+}
@@ -0,0 +1,138 @@
+package uci
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSAN(t *testing.T) {
+	// arrange
+	cases := []struct {
+		fen  string
+		move string
+		want string
+	}{
+		{fen: startPosFEN, move: "e2e4", want: "e4"},
+		{fen: startPosFEN, move: "g1f3", want: "Nf3"},
+		{fen: "r1bqkbnr/pppp1ppp/2n5/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R w KQkq - 2 3", move: "f3e5", want: "Nxe5"},
+		{fen: "rnbqkbnr/ppp1p1pp/8/3pPp2/8/8/PPPP1PPP/RNBQKBNR w KQkq f6 0 3", move: "e5f6", want: "exf6"},
+		{fen: "r3k2r/pppppppp/8/8/8/8/PPPPPPPP/R3K2R w KQkq - 0 1", move: "e1g1", want: "O-O"},
+		{fen: "r3k2r/pppppppp/8/8/8/8/PPPPPPPP/R3K2R b KQkq - 0 1", move: "e8c8", want: "O-O-O"},
+		{fen: "3r3k/4P3/8/8/8/8/8/K7 w - - 0 1", move: "e7d8q", want: "exd8=Q+"},
+		{fen: "3r3k/4P3/8/8/8/8/8/K7 w - - 0 1", move: "e7e8n", want: "e8=N"},
+		{fen: "6k1/5ppp/8/8/8/8/8/R5K1 w - - 0 1", move: "a1a8", want: "Ra8#"},
+		{fen: "4k3/8/8/8/8/8/8/R3K2R w - - 0 1", move: "a1d1", want: "Rd1"},
+		{fen: "4k3/8/8/8/8/8/4K3/R6R w - - 0 1", move: "a1d1", want: "Rad1"},
+		{fen: "4k3/8/8/8/8/8/4K3/R6R w - - 0 1", move: "h1f1", want: "Rhf1"},
+		{fen: "4k3/8/8/R7/8/8/8/R3K3 w - - 0 1", move: "a1a3", want: "R1a3"},
+		{fen: "4k3/8/8/8/8/8/8/R3K3 w - - 0 1", move: "a1a8", want: "Ra8+"},
+		{fen: "7k/2N5/8/8/8/2N1N3/8/4K3 w - - 0 1", move: "c3d5", want: "Nc3d5"},
+		{fen: "7k/2N5/8/8/8/2N1N3/8/4K3 w - - 0 1", move: "e3d5", want: "Ned5"},
+		{fen: "7k/2N5/8/8/8/2N1N3/8/4K3 w - - 0 1", move: "c7d5", want: "N7d5"},
+		{fen: "4k3/8/8/8/8/8/3N4/4K1N1 w - - 0 1", move: "g1f3", want: "Ngf3"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.fen+" "+c.move, func(t *testing.T) {
+			// act
+			b := FENtoBoard(c.fen)
+			got, err := b.SAN(c.move)
+
+			// assert
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != c.want {
+				t.Errorf("want: '%s' got: '%s'", c.want, got)
+			}
+		})
+	}
+}
+
+func TestParseSAN(t *testing.T) {
+	// arrange
+	cases := []struct {
+		fen     string
+		san     string
+		want    string
+		wantErr bool
+	}{
+		{fen: startPosFEN, san: "e4", want: "e2e4"},
+		{fen: startPosFEN, san: "Nf3", want: "g1f3"},
+		{fen: startPosFEN, san: "Ng1f3", want: "g1f3"},
+		{fen: startPosFEN, san: "Ng1-f3", want: "g1f3"},
+		{fen: startPosFEN, san: "e5", wantErr: true},
+		{fen: startPosFEN, san: "Nd2", wantErr: true},
+		{fen: startPosFEN, san: "Zf3", wantErr: true},
+		{fen: startPosFEN, san: "", wantErr: true},
+		{fen: "r1bqkbnr/pppp1ppp/2n5/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R w KQkq - 2 3", san: "Nxe5", want: "f3e5"},
+		{fen: "r1bqkbnr/pppp1ppp/2n5/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R w KQkq - 2 3", san: "Ne5!?", want: "f3e5"},
+		{fen: "rnbqkbnr/ppp1p1pp/8/3pPp2/8/8/PPPP1PPP/RNBQKBNR w KQkq f6 0 3", san: "exf6", want: "e5f6"},
+		{fen: "rnbqkbnr/ppp1p1pp/8/3pPp2/8/8/PPPP1PPP/RNBQKBNR w KQkq f6 0 3", san: "ef6", want: "e5f6"},
+		{fen: "r3k2r/pppppppp/8/8/8/8/PPPPPPPP/R3K2R w KQkq - 0 1", san: "O-O", want: "e1g1"},
+		{fen: "r3k2r/pppppppp/8/8/8/8/PPPPPPPP/R3K2R w KQkq - 0 1", san: "0-0-0", want: "e1c1"},
+		{fen: "r3k2r/pppppppp/8/8/8/8/PPPPPPPP/R3K2R b KQkq - 0 1", san: "O-O-O", want: "e8c8"},
+		{fen: "3r3k/4P3/8/8/8/8/8/K7 w - - 0 1", san: "exd8=Q+", want: "e7d8q"},
+		{fen: "3r3k/4P3/8/8/8/8/8/K7 w - - 0 1", san: "e8N", want: "e7e8n"},
+		{fen: "3r3k/4P3/8/8/8/8/8/K7 w - - 0 1", san: "e8", wantErr: true},
+		{fen: "3r3k/4P3/8/8/8/8/8/K7 w - - 0 1", san: "e8=K", wantErr: true},
+		{fen: "4k3/8/8/8/8/8/4K3/R6R w - - 0 1", san: "Rf1", wantErr: true},
+		{fen: "4k3/8/8/8/8/8/4K3/R6R w - - 0 1", san: "Rhf1", want: "h1f1"},
+		{fen: "7k/2N5/8/8/8/2N1N3/8/4K3 w - - 0 1", san: "Nc3d5", want: "c3d5"},
+		{fen: "7k/2N5/8/8/8/2N1N3/8/4K3 w - - 0 1", san: "Nexd5", want: "e3d5"},
+		{fen: "7k/2N5/8/8/8/2N1N3/8/4K3 w - - 0 1", san: "Ncd5", wantErr: true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.fen+" "+c.san, func(t *testing.T) {
+			// act
+			b := FENtoBoard(c.fen)
+			got, err := b.ParseSAN(c.san)
+
+			// assert
+			if c.wantErr {
+				if err == nil {
+					t.Errorf("want error, got: '%s'", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != c.want {
+				t.Errorf("want: '%s' got: '%s'", c.want, got)
+			}
+		})
+	}
+}
+
+func TestSANLineRoundTrip(t *testing.T) {
+	// arrange
+	moves := strings.Split("d2d4 g8f6 c2c4 e7e6 g2g3 f8b4 b1d2 d7d5 f1g2 e8g8 g1f3 b7b6 e1g1 c8b7 f3e5 a7a5 d1c2 c7c5 c4d5 b7d5 e2e4 d5b7 d4c5 d8c8 e5d3 b4d2 c1d2 f6e4 a1c1 e4d2 g2b7 c8b7 c2d2 f8d8 d2e3 b6c5 c1c5 b8d7 c5c4 d7b6 c4c5 a8c8 f1c1 h7h6 b2b3 c8c5 d3c5 b7d5 c5e4 b6c8 e4c3 d5a8 e3e4 a8b8 c1d1 c8e7 d1d8 b8d8", " ")
+	want := "d4 Nf6 c4 e6 g3 Bb4+ Nd2 d5 Bg2 O-O Nf3 b6 O-O Bb7 Ne5 a5 Qc2 c5 cxd5 Bxd5 e4 Bb7 dxc5 Qc8 Nd3 Bxd2 Bxd2 Nxe4 Rac1 Nxd2 Bxb7 Qxb7 Qxd2 Rd8 Qe3 bxc5 Rxc5 Nd7 Rc4 Nb6 Rc5 Rac8 Rfc1 h6 b3 Rxc5 Nxc5 Qd5 Ne4 Nc8 Nc3 Qa8 Qe4 Qb8 Rd1 Ne7 Rxd8+ Qxd8"
+
+	// act
+	b := FENtoBoard(startPosFEN)
+	line, err := b.SANLine(moves...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var parsed []string
+	for _, san := range line {
+		move, err := b.ParseSAN(san)
+		if err != nil {
+			t.Fatal(err)
+		}
+		parsed = append(parsed, move)
+		b.Moves(move)
+	}
+
+	// assert
+	if got := strings.Join(line, " "); got != want {
+		t.Errorf("\nwant: '%s'\ngot:  '%s'", want, got)
+	}
+	if got := strings.Join(parsed, " "); got != strings.Join(moves, " ") {
+		t.Errorf("\nwant: '%s'\ngot:  '%s'", strings.Join(moves, " "), got)
+	}
+}
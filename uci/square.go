@@ -0,0 +1,69 @@
+package uci
+
+import "fmt"
+
+// Square is a square on the board, from a1 = 0, b1 = 1 ... to h8 = 63.
+type Square int8
+
+// NoSquare is the missing square, e.g. no en passant square.
+const NoSquare Square = -1
+
+// ParseSquare returns the square for its name, e.g. "e4".
+func ParseSquare(s string) (Square, error) {
+	if !isSquare(s) {
+		return NoSquare, fmt.Errorf("square '%s' is malformed", s)
+	}
+	return makeSquare(int(s[0]-'a'), int(s[1]-'1')), nil
+}
+
+// makeSquare returns the square on the file and rank (0-7).
+func makeSquare(file, rank int) Square {
+	return Square(rank*8 + file)
+}
+
+// File returns the square's file, 0 for the a-file to 7 for the h-file.
+func (s Square) File() int {
+	return int(s) & 7
+}
+
+// Rank returns the square's rank, 0 for the first rank to 7 for the eighth.
+func (s Square) Rank() int {
+	return int(s) >> 3
+}
+
+func (s Square) String() string {
+	if s < 0 || s > 63 {
+		return "-"
+	}
+	return string([]byte{byte('a' + s.File()), byte('1' + s.Rank())})
+}
+
+func (s Square) bitboard() Bitboard {
+	return Bitboard(1) << uint(s)
+}
+
+// onBoard returns true if file and rank (0-7) are on the board.
+func onBoard(file, rank int) bool {
+	return file >= 0 && file < 8 && rank >= 0 && rank < 8
+}
+
+// Color is the side to move or the owner of a piece.
+type Color uint8
+
+const (
+	White Color = iota
+	Black
+)
+
+// Other returns the opponent's color.
+func (c Color) Other() Color {
+	return c ^ 1
+}
+
+// String returns the color as it's written in a FEN, "w" or "b".
+func (c Color) String() string {
+	if c == White {
+		return "w"
+	}
+	return "b"
+}
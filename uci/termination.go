@@ -0,0 +1,96 @@
+package uci
+
+// Termination is the reason a game is over.
+type Termination int
+
+const (
+	NotTerminated Termination = iota
+	Checkmate
+	Stalemate
+	InsufficientMaterial
+	FiftyMoveRule
+	ThreefoldRepetition
+)
+
+func (t Termination) String() string {
+	switch t {
+	case Checkmate:
+		return "checkmate"
+	case Stalemate:
+		return "stalemate"
+	case InsufficientMaterial:
+		return "insufficient material"
+	case FiftyMoveRule:
+		return "fifty-move rule"
+	case ThreefoldRepetition:
+		return "threefold repetition"
+	default:
+		return "not terminated"
+	}
+}
+
+// lightSquares is every light square, b1, d1 ... a2, c2 ... h8.
+const lightSquares Bitboard = 0x55aa55aa55aa55aa
+
+// Termination returns why the game is over in this position, or NotTerminated.
+// history is the game's position keys ending with this position, see
+// History; it may be nil if repetitions don't matter.
+func (b *Board) Termination(history History) Termination {
+	switch {
+	case b.IsCheckmate():
+		return Checkmate
+	case b.IsStalemate():
+		return Stalemate
+	case b.IsInsufficientMaterial():
+		return InsufficientMaterial
+	case b.IsFiftyMoveDraw():
+		return FiftyMoveRule
+	case b.IsThreefold(history):
+		return ThreefoldRepetition
+	default:
+		return NotTerminated
+	}
+}
+
+// IsCheckmate returns true if the side to move is in check and has no legal
+// moves.
+func (b *Board) IsCheckmate() bool {
+	return b.InCheck() && len(b.GenerateMoves()) == 0
+}
+
+// IsStalemate returns true if the side to move isn't in check and has no legal
+// moves.
+func (b *Board) IsStalemate() bool {
+	return !b.InCheck() && len(b.GenerateMoves()) == 0
+}
+
+// IsInsufficientMaterial returns true if neither side can mate: king against
+// king and a minor piece, or kings and bishops all on the same color squares.
+func (b *Board) IsInsufficientMaterial() bool {
+	heavy := b.pieces[WhitePawn] | b.pieces[BlackPawn] |
+		b.pieces[WhiteRook] | b.pieces[BlackRook] |
+		b.pieces[WhiteQueen] | b.pieces[BlackQueen]
+	if heavy != 0 {
+		return false
+	}
+
+	knights := b.pieces[WhiteKnight] | b.pieces[BlackKnight]
+	bishops := b.pieces[WhiteBishop] | b.pieces[BlackBishop]
+	if (knights | bishops).Count() <= 1 {
+		return true
+	}
+
+	return knights == 0 && (bishops&lightSquares == 0 || bishops&^lightSquares == 0)
+}
+
+// IsFiftyMoveDraw returns true if a draw can be claimed under the fifty-move
+// rule. Checkmate on the hundredth ply takes precedence.
+func (b *Board) IsFiftyMoveDraw() bool {
+	return b.PliesToFiftyMoveRule() == 0 && !b.IsCheckmate()
+}
+
+// IsThreefold returns true if the position has occurred at least three times
+// in the history.
+func (b *Board) IsThreefold(history History) bool {
+	return history.Repetitions(b.key) >= 3
+}
@@ -0,0 +1,52 @@
+package uci
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTermination(t *testing.T) {
+	// arrange
+	cases := []struct {
+		name  string
+		fen   string
+		moves string
+		want  Termination
+	}{
+		{name: "start position", fen: startPosFEN, want: NotTerminated},
+		{name: "fool's mate", fen: startPosFEN, moves: "f2f3 e7e5 g2g4 d8h4", want: Checkmate},
+		{name: "back rank mate", fen: "R5k1/5ppp/8/8/8/8/8/6K1 b - - 0 1", want: Checkmate},
+		{name: "stalemate", fen: "7k/5Q2/6K1/8/8/8/8/8 b - - 0 1", want: Stalemate},
+		{name: "kings only", fen: "8/8/4k3/8/8/4K3/8/8 w - - 0 1", want: InsufficientMaterial},
+		{name: "king and knight", fen: "8/8/4k3/8/8/4K3/8/6N1 w - - 0 1", want: InsufficientMaterial},
+		{name: "king and bishop", fen: "8/8/4k3/8/8/4K3/8/5b2 w - - 0 1", want: InsufficientMaterial},
+		{name: "bishops on the same color", fen: "8/8/4k3/8/8/4K3/2B5/5b2 w - - 0 1", want: InsufficientMaterial},
+		{name: "bishops on opposite colors", fen: "8/8/4k3/8/8/4K3/8/2B2b2 w - - 0 1", want: NotTerminated},
+		{name: "two knights", fen: "8/8/4k3/8/8/4K3/8/1N4N1 w - - 0 1", want: NotTerminated},
+		{name: "king and pawn", fen: "8/8/4k3/8/8/4K3/4P3/8 w - - 0 1", want: NotTerminated},
+		{name: "fifty-move rule", fen: "8/8/4k3/8/8/4K3/4R3/8 w - - 100 80", want: FiftyMoveRule},
+		{name: "fifty-move rule, one ply left", fen: "8/8/4k3/8/8/4K3/4R3/8 w - - 99 80", want: NotTerminated},
+		{name: "mate on the hundredth ply", fen: "R5k1/5ppp/8/8/8/8/8/6K1 b - - 100 80", want: Checkmate},
+		{name: "threefold repetition", fen: startPosFEN, moves: "g1f3 g8f6 f3g1 f6g8 g1f3 g8f6 f3g1 f6g8", want: ThreefoldRepetition},
+		{name: "twofold repetition", fen: startPosFEN, moves: "g1f3 g8f6 f3g1 f6g8", want: NotTerminated},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			b := FENtoBoard(c.fen)
+			history := History{b.Key()}
+			for _, move := range strings.Fields(c.moves) {
+				b.Moves(move)
+				history = append(history, b.Key())
+			}
+
+			// act
+			got := b.Termination(history)
+
+			// assert
+			if got != c.want {
+				t.Errorf("want: %s got: %s", c.want, got)
+			}
+		})
+	}
+}
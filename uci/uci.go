@@ -15,11 +15,10 @@ import (
 	"sync/atomic"
 	"syscall"
 	"time"
-
-	"trollfish/stockfish"
 )
 
 const startPosFEN = "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1"
+const startPosKey uint64 = 0x463b96181691fc9c // Polyglot key of startPosFEN
 const defaultThreads = 28
 
 //const hashMemory = 40960
@@ -28,6 +27,16 @@ const hashMemory = 7168 // 256*28
 const defaultMultiPV = 5
 const agroMultiPV = 2
 
+// when not agro, don't take a repetition (scored 0.00) if Stockfish has us
+// above drawAvoidEval, and take one if it has us below drawSeekEval
+const drawAvoidEval = 50
+const drawSeekEval = -150
+
+// a book move is checked with a search this long, and rejected if Stockfish
+// has us below the BookEvalFloor option
+const bookCheckMoveTime = 250
+const defaultBookEvalFloor = -300
+
 // TODO: get path from config file
 const stockfishPath = "/home/jud/projects/trollfish/stockfish/stockfish"
 
@@ -36,10 +45,40 @@ type UCI struct {
 	author  string
 	options []Option
 
-	fen string
+	fen     string
+	board   Board
+	history History
+	game    gameRecord
+
+	// opening is the last opening reported, see reportOpening
+	opening Opening
+
+	// opponent is from the UCI_Opponent option, and picks the repertoire's
+	// lines
+	opponent Opponent
+
+	// recordGames saves each game as PGN, see saveGame
+	recordGames bool
+
+	// book is the BookFile option's Polyglot book, or nil
+	book *PolyglotBook
 
-	started int64
-	playBad bool
+	// repertoire is the troll's opening lines, see CasualBookMove
+	repertoire *Repertoire
+
+	// learning adjusts book weights by how our book moves scored;
+	// freezeLearning stops it learning from new games
+	learning       *BookLearning
+	freezeLearning bool
+
+	// bookCheck is the book move being verified by Stockfish, or nil; see
+	// verifyBookMove. It's guarded by moveListMtx.
+	bookCheck     *bookCheck
+	bookEvalFloor int
+
+	started  int64
+	playBad  bool
+	chess960 bool
 
 	moveListMtx     sync.Mutex
 	moveListNodes   int
@@ -53,11 +92,16 @@ type UCI struct {
 	gameAgro        bool
 	startAgro       bool
 
-	sf *stockfish.StockFish
+	sf Engine
 
 	ctx    context.Context
 	cancel context.CancelFunc
 
+	// in and out are stdin and stdout, except when driving the proxy from
+	// another command such as RunEPD
+	in  io.Reader
+	out io.Writer
+
 	mtxStdout sync.Mutex
 	log       io.WriteCloser
 }
@@ -76,13 +120,16 @@ type Info struct {
 	PV       string
 }
 
-func (m Info) String() string {
-	var score string
+// scoreString returns the score as UCI writes it, "cp <n>" or "mate <n>".
+func (m Info) scoreString() string {
 	if m.Mate == 0 {
-		score = fmt.Sprintf("cp %d", m.Score)
-	} else {
-		score = fmt.Sprintf("mate %d", m.Mate)
+		return fmt.Sprintf("cp %d", m.Score)
 	}
+	return fmt.Sprintf("mate %d", m.Mate)
+}
+
+func (m Info) String() string {
+	score := m.scoreString()
 	return fmt.Sprintf("depth %d seldepth %d multipv %d score %s nodes %d nps %d hashfull %d tbhits %d time %d pv %s",
 		m.Depth, m.SelDepth, m.MultiPV, score, m.Nodes, m.NPS, m.HashFull, m.TBHits, m.Time, m.PV,
 	)
@@ -90,15 +137,23 @@ func (m Info) String() string {
 
 func New(name, author string, options ...Option) *UCI {
 	return &UCI{
-		name:        name,
-		author:      author,
-		options:     options,
-		gameMultiPV: defaultMultiPV,
+		name:          name,
+		author:        author,
+		options:       options,
+		gameMultiPV:   defaultMultiPV,
+		recordGames:   true,
+		repertoire:    mustParseRepertoire(defaultRepertoire),
+		learning:      NewBookLearning(),
+		bookEvalFloor: defaultBookEvalFloor,
+		in:            os.Stdin,
+		out:           os.Stdout,
 	}
 }
 
 func (u *UCI) ResetGame() {
+	u.saveGame()
 	u.sf.Write("ucinewgame")
+	u.moveListMtx.Lock()
 	if u.startAgro {
 		u.gameMultiPV = agroMultiPV
 	} else {
@@ -109,7 +164,11 @@ func (u *UCI) ResetGame() {
 	u.gameMateIn = 0
 	u.gameEval = 0
 	u.gameAgro = u.startAgro
-	u.sf.Write(fmt.Sprintf("setoption name MultiPV value %d", u.gameMultiPV))
+	u.history = nil
+	multiPV := u.gameMultiPV
+	u.moveListMtx.Unlock()
+	u.opening = Opening{}
+	u.sf.Write(fmt.Sprintf("setoption name MultiPV value %d", multiPV))
 }
 
 func (u *UCI) Start(ctx context.Context) (context.Context, context.CancelFunc) {
@@ -128,13 +187,33 @@ func (u *UCI) Start(ctx context.Context) (context.Context, context.CancelFunc) {
 
 	u.logInfo("=========================================")
 
+	if learning, err := LoadBookLearning(bookLearnFile); err != nil {
+		u.logInfo(fmt.Sprintf("ERR: %v", err))
+	} else {
+		u.learning = learning
+	}
+
 	u.ctx, u.cancel = context.WithCancel(ctx)
 
+	sf, err := StartStockfish(u.ctx, u.logInfo)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	u.startEngine(sf)
+	u.readCommands()
+
+	return u.ctx, u.cancel
+}
+
+// readCommands reads commands from in, one per line, and handles them until
+// the proxy's context is done.
+func (u *UCI) readCommands() {
 	c := make(chan string, 512)
 
 	go func() {
 		defer close(c)
-		r := bufio.NewScanner(os.Stdin)
+		r := bufio.NewScanner(u.in)
 
 		for r.Scan() {
 			select {
@@ -151,22 +230,11 @@ func (u *UCI) Start(ctx context.Context) (context.Context, context.CancelFunc) {
 		}
 	}()
 
-	sf, err := stockfish.Start(u.ctx, stockfishPath, u.logInfo)
-	if err != nil {
-		log.Fatal(err)
-	}
-
-	u.sf = sf
-
-	go u.stockFishReadLoop()
-
 	go func() {
 		for line := range c {
 			u.parseLine(line)
 		}
 	}()
-
-	return u.ctx, u.cancel
 }
 
 func (u *UCI) logInfo(s string) {
@@ -174,7 +242,7 @@ func (u *UCI) logInfo(s string) {
 }
 
 func (u *UCI) stockFishReadLoop() {
-	for line := range u.sf.Output {
+	for line := range u.sf.Output() {
 		line = strings.TrimSpace(line)
 		if line == "" {
 			continue
@@ -203,71 +271,21 @@ func (u *UCI) stockFishReadLoop() {
 				break
 			}
 
-			var move Info
-		infoLoop:
-			for i := 1; i < len(parts); i += 2 {
-				if i == len(parts)-1 {
-					break
-				}
-
-				key := parts[i]
-
-				var n int
-
-				if key == "score" {
-					if parts[i+1] == "cp" {
-						key = "score.cp"
-						n = atoi(parts[i+2])
-					}
-					if parts[i+1] == "mate" {
-						key = "score.mate"
-						n = atoi(parts[i+2])
-					}
-					i++
-					if i+2 < len(parts) && (parts[i+2] == "lowerbound" || parts[i+2] == "upperbound") {
-						// ignore
-						i++
-					}
-				} else {
-					n = atoi(parts[i+1])
-				}
-
-				switch key {
-				case "score.cp":
-					move.Score = n
-				case "score.mate":
-					move.Mate = n
-				case "depth":
-					move.Depth = n
-				case "seldepth":
-					move.SelDepth = n
-				case "multipv":
-					move.MultiPV = n
-				case "nodes":
-					move.Nodes = n
-				case "nps":
-					move.NPS = n
-				case "hashfull":
-					move.HashFull = n
-				case "tbhits":
-					move.TBHits = n
-				case "time":
-					move.Time = n
-				case "currmove", "currmovenumber":
-					// ignore
-				case "pv":
-					move.PV = strings.Join(parts[i+1:], " ")
-					break infoLoop
-				default:
-					u.logInfo(fmt.Sprintf("unknown key '%s': %s", key, strings.Join(parts, " ")))
-				}
-			}
+			move := parseInfo(parts, u.logInfo)
 
 			if move.PV == "" {
 				break
 			}
 
 			u.moveListMtx.Lock()
+			if u.bookCheck != nil {
+				// a book move check isn't our search, don't list or print it
+				if move.Depth >= u.bookCheck.eval.Depth {
+					u.bookCheck.eval = move
+				}
+				u.moveListMtx.Unlock()
+				break
+			}
 			if move.Nodes != u.moveListNodes {
 				if len(u.moveList) > 0 {
 					prevTime := u.moveList[0].Time
@@ -303,6 +321,10 @@ func (u *UCI) stockFishReadLoop() {
 			u.moveListMtx.Unlock()
 
 		case "bestmove":
+			if u.finishBookCheck() {
+				break
+			}
+
 			if line == "bestmove (none)" {
 				u.WriteLine(line)
 				break
@@ -323,6 +345,11 @@ func (u *UCI) stockFishReadLoop() {
 
 			if u.gameAgro || engineMove.Score >= 2000 || engineMove.Mate > 0 {
 				u.gameAgro = true
+			} else if drawMove, ok := u.findThreefold(); ok && (engineMove.Score < drawSeekEval || engineMove.Mate < 0) {
+				// we're losing, take the draw
+				u.gameMateIn = 0
+				bestMove = drawMove
+				u.logInfo(fmt.Sprintf("seeking threefold repetition with %s, sf_move_eval: %d", strings.Split(drawMove.PV, " ")[0], engineMove.Score))
 			} else {
 				u.gameMateIn = 0
 
@@ -338,6 +365,12 @@ func (u *UCI) stockFishReadLoop() {
 						continue
 					}
 
+					// a repetition or fifty-move draw scores 0.00, which looks like
+					// the equality we're after; don't throw away a better position for it
+					if engineMove.Score > drawAvoidEval && (u.completesThreefold(move.PV) || u.reachesFiftyMoveRule(move.PV)) {
+						continue
+					}
+
 					// attempt to maintain equality until we hit agro
 					dist := move.Score
 					if dist < 0 {
@@ -368,41 +401,60 @@ func (u *UCI) stockFishReadLoop() {
 			u.moveListNodes = 0
 
 			uciMove := strings.Split(bestMove.PV, " ")[0]
+			if !u.board.empty() && !u.board.IsLegalMove(uciMove) {
+				u.logInfo(fmt.Sprintf("!!! WARNING %s is not legal in '%s', playing %s", uciMove, u.board.FEN(), parts[1]))
+				bestMove = engineMove
+				uciMove = parts[1]
+			}
 
 			u.gameMateIn = bestMove.Mate
 			u.gameEval = bestMove.Score
 
+			sfMove := strings.Split(engineMove.PV, " ")[0]
+			if !u.board.empty() {
+				u.game.addMove(&u.board, uciMove, moveComment(&u.board, uciMove, sfMove, bestMove, engineMove, u.gameAgro))
+			}
+
+			board := u.board.clone()
+			agro, activeColor := u.gameAgro, u.gameActiveColor
+
 			u.moveListMtx.Unlock()
 
 			evalHuman := float64(bestMove.Score) / 100
-			if bestMove.Score != 0 && u.gameActiveColor == "b" {
+			if bestMove.Score != 0 && activeColor == "b" {
 				evalHuman *= -1
 			}
 			evalString := fmt.Sprintf("%0.2f", evalHuman)
 
 			if bestMove.Mate != 0 {
 				mateHuman := bestMove.Mate
-				if u.gameActiveColor == "b" {
+				if activeColor == "b" {
 					mateHuman *= -1
 				}
 				evalString = fmt.Sprintf("M%d", mateHuman)
 			}
 
-			addl := fmt.Sprintf("eval %s agro %v", evalString, u.gameAgro)
+			addl := fmt.Sprintf("eval %s agro %v", evalString, agro)
 			if uciMove == parts[1] {
 				u.WriteLine(line + " " + addl)
 			} else {
 				u.WriteLine(fmt.Sprintf("bestmove %s %s", uciMove, addl))
 
-				if u.gameAgro {
+				if agro {
 					u.logInfo(fmt.Sprintf("!!! WARNING %s != %s", parts[1], uciMove))
 				}
 			}
 
-			u.logInfo(fmt.Sprintf("play_bad: %v agro: %v sf_move: %s sf_move_eval: %d played_move: %s eval: %d",
-				u.playBad, u.gameAgro,
-				strings.Split(engineMove.PV, " ")[0], engineMove.Score,
-				uciMove, bestMove.Score,
+			analysis := "-"
+			if m, err := ParseMove(uciMove); err == nil && !board.empty() {
+				analysis = board.AnalyzeMove(m).String()
+			}
+
+			u.logInfo(fmt.Sprintf("play_bad: %v agro: %v sf_move: %s (%s) sf_move_eval: %d played_move: %s (%s) eval: %d analysis: %s",
+				u.playBad, agro,
+				sfMove, board.sanOrUCI(sfMove), engineMove.Score,
+				uciMove, board.sanOrUCI(uciMove), bestMove.Score,
+				analysis,
 			))
 
 		default:
@@ -414,6 +466,72 @@ func (u *UCI) stockFishReadLoop() {
 	u.logInfo("stockfish read loop exited")
 }
 
+// parseInfo parses an 'info' line from Stockfish, split on spaces. Unknown
+// keys are logged.
+func parseInfo(parts []string, logInfo func(string)) Info {
+	var move Info
+infoLoop:
+	for i := 1; i < len(parts); i += 2 {
+		if i == len(parts)-1 {
+			break
+		}
+
+		key := parts[i]
+
+		var n int
+
+		if key == "score" {
+			if parts[i+1] == "cp" {
+				key = "score.cp"
+				n = atoi(parts[i+2])
+			}
+			if parts[i+1] == "mate" {
+				key = "score.mate"
+				n = atoi(parts[i+2])
+			}
+			i++
+			if i+2 < len(parts) && (parts[i+2] == "lowerbound" || parts[i+2] == "upperbound") {
+				// ignore
+				i++
+			}
+		} else {
+			n = atoi(parts[i+1])
+		}
+
+		switch key {
+		case "score.cp":
+			move.Score = n
+		case "score.mate":
+			move.Mate = n
+		case "depth":
+			move.Depth = n
+		case "seldepth":
+			move.SelDepth = n
+		case "multipv":
+			move.MultiPV = n
+		case "nodes":
+			move.Nodes = n
+		case "nps":
+			move.NPS = n
+		case "hashfull":
+			move.HashFull = n
+		case "tbhits":
+			move.TBHits = n
+		case "time":
+			move.Time = n
+		case "currmove", "currmovenumber":
+			// ignore
+		case "pv":
+			move.PV = strings.Join(parts[i+1:], " ")
+			break infoLoop
+		default:
+			logInfo(fmt.Sprintf("unknown key '%s': %s", key, strings.Join(parts, " ")))
+		}
+	}
+
+	return move
+}
+
 func (u *UCI) parseLine(line string) {
 	u.logInfo(fmt.Sprintf("-> %s", line))
 
@@ -432,19 +550,18 @@ func (u *UCI) parseLine(line string) {
 	case "ucinewgame":
 		u.ResetGame()
 	case "setoption":
-		if len(parts) > 4 {
-			key := parts[2] // TODO: ignores that a key can be more than one word
-			val := parts[4]
-			u.SetOption(key, val)
-		}
+		u.setOptionRaw(parts[1:]...)
 	case "position":
 		u.SetPosition(parts[1:]...)
 	case "stop":
+		u.stopBookCheck()
 		u.sf.Write(line)
 	case "ponderhit":
 		u.sf.Write("ponderhit")
 	case "go":
 		u.Go(parts[1:]...)
+	case "d":
+		u.Display(parts[1:]...)
 	case "":
 	// no-op
 	default:
@@ -454,6 +571,7 @@ func (u *UCI) parseLine(line string) {
 }
 
 func (u *UCI) Quit() {
+	u.saveGame()
 	u.cancel()
 	u.sf.Quit()
 }
@@ -463,6 +581,7 @@ func (u *UCI) SetUCI() {
 	for _, o := range u.options {
 		switch o.Type {
 		case OptionTypeCheck:
+			opts = append(opts, fmt.Sprintf("option name %s type check default %s", o.Name, o.DefaultValue()))
 		case OptionTypeSpin:
 			opts = append(opts, fmt.Sprintf("option name %s type spin default %s min %d max %d", o.Name, o.DefaultValue(), o.Min, o.Max))
 		case OptionTypeCombo:
@@ -502,10 +621,38 @@ func (u *UCI) SetOption(name, value string) {
 	case "playbad":
 		u.playBad = value == "true"
 	case "startagro":
+		u.moveListMtx.Lock()
 		u.startAgro = value == "true"
 		u.gameAgro = true
+		u.moveListMtx.Unlock()
 	case "syzygypath":
 		u.sf.Write(fmt.Sprintf("setoption name SyzygyPath value %s", value))
+	case "bookfile":
+		u.SetBookFile(value)
+	case "repertoire":
+		u.SetRepertoireFile(value)
+	case "freezebooklearning":
+		u.freezeLearning = value == "true"
+	case "uci_opponent":
+		o, err := ParseOpponent(value)
+		if err != nil {
+			u.WriteLine(fmt.Sprintf("info string ERR: %v", err))
+			return
+		}
+		u.opponent = o
+		u.logInfo(fmt.Sprintf("opponent: %s", o))
+	case "bookevalfloor":
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			u.WriteLine(fmt.Sprintf("info option BookEvalFloor value %s invalid", value))
+			return
+		}
+		u.moveListMtx.Lock()
+		u.bookEvalFloor = n
+		u.moveListMtx.Unlock()
+	case "uci_chess960":
+		u.chess960 = value == "true"
+		u.sf.Write(fmt.Sprintf("setoption name UCI_Chess960 value %v", u.chess960))
 	case "ponder":
 		u.sf.Write(fmt.Sprintf("setoption name Ponder value %s", value))
 
@@ -514,6 +661,42 @@ func (u *UCI) SetOption(name, value string) {
 	}
 }
 
+// SetBookFile loads the Polyglot book for the BookFile option. An empty value
+// turns the book off.
+func (u *UCI) SetBookFile(path string) {
+	if path == "" || path == "<empty>" {
+		u.book = nil
+		return
+	}
+
+	book, err := LoadPolyglotBook(path)
+	if err != nil {
+		u.WriteLine(fmt.Sprintf("info string ERR: %v", err))
+		return
+	}
+
+	u.book = book
+	u.logInfo(fmt.Sprintf("book_file: %s entries: %d", path, len(book.Entries)))
+}
+
+// SetRepertoireFile loads the repertoire for the Repertoire option. An empty
+// value goes back to the built-in repertoire.
+func (u *UCI) SetRepertoireFile(path string) {
+	if path == "" || path == "<empty>" {
+		u.repertoire = mustParseRepertoire(defaultRepertoire)
+		return
+	}
+
+	rep, err := LoadRepertoire(path)
+	if err != nil {
+		u.WriteLine(fmt.Sprintf("info string ERR: %v", err))
+		return
+	}
+
+	u.repertoire = rep
+	u.logInfo(fmt.Sprintf("repertoire: %s positions: %d", path, rep.size()))
+}
+
 func (u *UCI) setOptionRaw(v ...string) {
 	if len(v) == 0 {
 		return
@@ -543,30 +726,54 @@ func (u *UCI) setOptionRaw(v ...string) {
 	}
 
 	var value string
-	for ; i < len(v); i++ {
+	for i++; i < len(v); i++ {
 		if value != "" {
 			value += " "
 		}
 		value += v[i]
 	}
+
+	u.SetOption(name, value)
 }
 
 func (u *UCI) Go(v ...string) {
+	if len(v) > 1 && v[0] == "perft" {
+		u.Perft(atoi(v[1]))
+		return
+	}
+
+	if u.answerGameOver() {
+		return
+	}
+
 	u.moveListMtx.Lock()
 	u.moveList = nil
 	u.moveListPrinted = false
 	u.moveListNodes = 0
 	u.moveListMtx.Unlock()
 
-	if u.fen == startPosFEN {
-		move := getFirstMove()
-		u.logInfo(fmt.Sprintf("book_move: %s", move))
-		u.WriteLine("bestmove " + move)
-		return
+	// the book is only used at the start or in a timed game
+	if u.atStartPos() || (len(v) > 1 && !u.gameAgro && v[0] == "wtime") {
+		if move := u.BookMove(); move != "" {
+			u.verifyBookMove(move, v)
+			return
+		}
 	}
 
+	u.search(v)
+}
+
+// search has Stockfish search for our move, managing our time in a timed game.
+func (u *UCI) search(v []string) {
+	// a rejected book move is searched from Stockfish's read loop, so the game
+	// state is read under the lock
+	u.moveListMtx.Lock()
+	gameAgro, activeColor, moveCount := u.gameAgro, u.gameActiveColor, u.gameMoveCount
+	mateIn, eval := u.gameMateIn, u.gameEval
+	u.moveListMtx.Unlock()
+
 	// passthroughs
-	if len(v) <= 1 || u.gameAgro {
+	if len(v) <= 1 || gameAgro {
 		u.sf.Write(fmt.Sprintf("go %s", strings.Join(v, " ")))
 		return
 	}
@@ -592,14 +799,8 @@ func (u *UCI) Go(v ...string) {
 		}
 	}
 
-	if move := u.BookMove(); move != "" {
-		u.logInfo(fmt.Sprintf("book_move: %s", move))
-		u.WriteLine("bestmove " + move)
-		return
-	}
-
 	var ourTime, oppTime, ourInc, oppInc int
-	if u.gameActiveColor == "w" {
+	if activeColor == "w" {
 		ourTime, ourInc = wtime, winc
 		oppTime, oppInc = btime, binc
 	} else {
@@ -616,7 +817,7 @@ func (u *UCI) Go(v ...string) {
 	veryLowTime := ourTime < 5_000
 
 	u.sf.Write(fmt.Sprintf("info string our_time: %d+%d opp_time: %d+%d active_color: %s %v low_time: %v very_low_time: %v",
-		ourTime, ourInc, oppTime, oppInc, u.gameActiveColor, v, lowTime, veryLowTime))
+		ourTime, ourInc, oppTime, oppInc, activeColor, v, lowTime, veryLowTime))
 
 	// don't tell SF we're in a time control
 	// TODO: improve time management
@@ -625,28 +826,28 @@ func (u *UCI) Go(v ...string) {
 	moveTime := 1000 + rand.Intn(500)
 	mate := false
 
-	if u.gameMoveCount < 5 {
+	if moveCount < 5 {
 		moveTime = 250 + rand.Intn(500)
-	} else if u.gameMateIn > 0 {
+	} else if mateIn > 0 {
 		agro = true
 		mate = true
-		moveTime = max(250, 75*u.gameMateIn)
-	} else if u.gameEval > 800 {
+		moveTime = max(250, 75*mateIn)
+	} else if eval > 800 {
 		agro = true
-	} else if u.gameMoveCount >= 23 && u.gameMoveCount < 35 {
-		if u.gameEval < 150 {
+	} else if moveCount >= 23 && moveCount < 35 {
+		if eval < 150 {
 			agro = true
 			moveTime = 2000 + rand.Intn(1000)
 		}
-	} else if u.gameMoveCount >= 35 {
+	} else if moveCount >= 35 {
 		agro = true
-		if u.gameEval < 350 {
+		if eval < 350 {
 			moveTime = 1500 + rand.Intn(1000)
 		}
 	}
 
 	// we're losing, stop to think
-	ponderEval := u.gameEval < -60 || (u.gameEval > 60 && u.gameEval < 400)
+	ponderEval := eval < -60 || (eval > 60 && eval < 400)
 	if ponderEval && ourTime > (oppTime/2) {
 		moveTime = 3500 + rand.Intn(1000)
 	}
@@ -665,7 +866,7 @@ func (u *UCI) Go(v ...string) {
 	origMoveTime := moveTime
 	moveTime = min(moveTime, maxTime)
 	moveTime = max(moveTime, minTimeBasedOnInc)
-	if u.gameEval > 2000 {
+	if eval > 2000 {
 		if ourTime > 2500 {
 			moveTime = 2500
 		} else {
@@ -697,6 +898,83 @@ func (u *UCI) Go(v ...string) {
 	u.sf.Write(fmt.Sprintf("go movetime %d", moveTime))
 }
 
+// answerGameOver answers 'go' without asking Stockfish when the game is
+// already over, and returns false if it isn't. A fifty-move or threefold draw
+// has to be claimed and the GUI may play on, so it's searched as usual.
+func (u *UCI) answerGameOver() bool {
+	u.moveListMtx.Lock()
+	b, history := u.board.clone(), u.history
+	u.moveListMtx.Unlock()
+
+	if b.empty() {
+		return false
+	}
+
+	t := b.Termination(history)
+	switch t {
+	case NotTerminated:
+		return false
+	case FiftyMoveRule, ThreefoldRepetition:
+		u.logInfo(fmt.Sprintf("draw_claimable: %s", t))
+		u.WriteLine(fmt.Sprintf("info string draw can be claimed: %s", t))
+		return false
+	}
+
+	// there's nothing to play after mate or stalemate; with insufficient
+	// material the result doesn't depend on the move
+	move := "(none)"
+	if moves := b.GenerateMoves(); len(moves) != 0 {
+		move = moves[0].String()
+	}
+
+	u.logInfo(fmt.Sprintf("game_over: %s bestmove: %s", t, move))
+	u.WriteLines(fmt.Sprintf("info string game over: %s", t), "bestmove "+move)
+
+	return true
+}
+
+// Perft prints the node count below each legal move in the current position,
+// in the same format as Stockfish's 'go perft'.
+func (u *UCI) Perft(depth int) {
+	u.moveListMtx.Lock()
+	b := u.board.clone()
+	u.moveListMtx.Unlock()
+
+	if b.empty() {
+		b = FENtoBoard(startPosFEN)
+	}
+
+	var nodes int
+	var lines []string
+	for _, r := range b.Divide(depth) {
+		nodes += r.Nodes
+		lines = append(lines, r.String())
+	}
+	lines = append(lines, "", fmt.Sprintf("Nodes searched: %d", nodes), "")
+
+	u.WriteLines(lines...)
+}
+
+// Display prints the proxy's board in the format of Stockfish's 'd' command,
+// followed by the troll state. 'd unicode' draws the pieces as chess symbols.
+func (u *UCI) Display(v ...string) {
+	u.moveListMtx.Lock()
+	b := u.board.clone()
+	agro, eval, multiPV := u.gameAgro, u.gameEval, u.gameMultiPV
+	u.moveListMtx.Unlock()
+
+	if b.empty() {
+		b = FENtoBoard(startPosFEN)
+	}
+
+	unicode := len(v) > 0 && v[0] == "unicode"
+
+	lines := append([]string{""}, b.Display(unicode)...)
+	lines = append(lines, fmt.Sprintf("Troll: agro %v eval %d multipv %d", agro, eval, multiPV), "")
+
+	u.WriteLines(lines...)
+}
+
 func (u *UCI) SetPosition(v ...string) {
 	if len(v) == 0 {
 		return
@@ -704,8 +982,6 @@ func (u *UCI) SetPosition(v ...string) {
 
 	cmd := v[0]
 
-	u.sf.Write(fmt.Sprintf("position %s", strings.Join(v, " ")))
-
 	if cmd == "fen" {
 		var fenEnd int
 		for fenEnd = 1; fenEnd < len(v); fenEnd++ {
@@ -713,15 +989,22 @@ func (u *UCI) SetPosition(v ...string) {
 				break
 			}
 		}
-		u.fen = strings.Join(v[1:fenEnd], " ")
-		b := FENtoBoard(u.fen)
+		b, err := ParseFEN(strings.Join(v[1:fenEnd], " "))
+		if err != nil {
+			// don't forward a position Stockfish can't handle either
+			u.WriteLine(fmt.Sprintf("info string ERR: %v", err))
+			return
+		}
+
+		var moves []string
+		history := History{b.Key()}
+		start := b
 		if len(v) != fenEnd && v[fenEnd] == "moves" {
-			moves := v[fenEnd+1:]
-			b.Moves(moves...)
+			moves, history = u.applyMoves(&b, v[fenEnd+1:])
 		}
-		u.fen = b.FEN()
-		u.gameMoveCount = atoi(b.FullMove)
-		u.gameActiveColor = b.ActiveColor
+		u.sf.Write(positionCommand(v[:fenEnd], moves))
+		u.setBoard(b, history)
+		u.recordPosition(start, moves)
 
 		u.WriteLine(fmt.Sprintf("info fen set to '%s' move %d, %s to play", u.fen, u.gameMoveCount, u.gameActiveColor))
 		return
@@ -734,7 +1017,10 @@ func (u *UCI) SetPosition(v ...string) {
 	}
 
 	if len(v) == 1 {
-		u.fen = startPosFEN
+		u.sf.Write("position startpos")
+		b := FENtoBoard(startPosFEN)
+		u.setBoard(b, History{b.Key()})
+		u.recordPosition(b, nil)
 		u.WriteLine(fmt.Sprintf("info fen set to '%s', move 1, w to play", u.fen))
 		return
 	}
@@ -742,21 +1028,136 @@ func (u *UCI) SetPosition(v ...string) {
 	cmd = v[1]
 
 	if cmd != "moves" {
-		u.fen = startPosFEN
+		u.sf.Write("position startpos")
+		b := FENtoBoard(startPosFEN)
+		u.setBoard(b, History{b.Key()})
+		u.recordPosition(b, nil)
 		u.WriteLine(fmt.Sprintf("info fen set to '%s'", u.fen))
 		u.WriteLine(fmt.Sprintf("info ERR: position startpos '%s' command unknown", cmd))
 		return
 	}
 
-	moves := v[2:]
+	b := FENtoBoard(startPosFEN)
+	moves, history := u.applyMoves(&b, v[2:])
+	u.sf.Write(positionCommand(v[:1], moves))
+	u.setBoard(b, history)
+	u.recordPosition(FENtoBoard(startPosFEN), moves)
+
+	u.WriteLine(fmt.Sprintf("info fen set to '%s' move %d, %s to play", u.fen, u.gameMoveCount, u.gameActiveColor))
+}
+
+// findThreefold returns the first move in the move list which repeats the
+// position for the third time. The caller must hold moveListMtx.
+func (u *UCI) findThreefold() (Info, bool) {
+	for _, move := range u.moveList {
+		if move.Mate == 0 && u.completesThreefold(move.PV) {
+			return move, true
+		}
+	}
+	return Info{}, false
+}
+
+// completesThreefold returns true if the first move of the PV repeats the
+// position for the third time. The caller must hold moveListMtx.
+func (u *UCI) completesThreefold(pv string) bool {
+	move := strings.Split(pv, " ")[0]
+	if u.board.empty() || !u.board.IsLegalMove(move) {
+		return false
+	}
+	return u.history.RepetitionsAfter(&u.board, move) >= 3
+}
+
+// reachesFiftyMoveRule returns true if a draw can be claimed under the
+// fifty-move rule after the first move of the PV. The caller must hold
+// moveListMtx.
+func (u *UCI) reachesFiftyMoveRule(pv string) bool {
+	move := strings.Split(pv, " ")[0]
+	if u.board.empty() || u.board.PliesToFiftyMoveRule() > 1 || !u.board.IsLegalMove(move) {
+		return false
+	}
+	c := u.board.clone()
+	c.Moves(move)
+	return c.PliesToFiftyMoveRule() == 0
+}
+
+// applyMoves plays the moves on b, stopping at the first move which isn't legal.
+// Moves can be in UCI or SAN notation; the moves played are returned in UCI
+// notation along with the key of every position reached, starting with b.
+func (u *UCI) applyMoves(b *Board, moves []string) ([]string, History) {
+	b.Chess960 = u.chess960
+
+	played := make([]string, 0, len(moves))
+	history := History{b.Key()}
+	for _, move := range moves {
+		if !b.IsLegalMove(move) {
+			uciMove, err := b.ParseSAN(move)
+			if err != nil {
+				u.WriteLine(fmt.Sprintf("info string ERR: illegal move '%s' in position '%s'", move, b.FEN()))
+				break
+			}
+			move = uciMove
+		}
+		b.Moves(move)
+		played = append(played, move)
+		history = append(history, b.Key())
+	}
+	return played, history
+}
+
+// positionCommand returns the 'position' command to forward to Stockfish.
+func positionCommand(pos []string, moves []string) string {
+	cmd := "position " + strings.Join(pos, " ")
+	if len(moves) != 0 {
+		cmd += " moves " + strings.Join(moves, " ")
+	}
+	return cmd
+}
+
+func (u *UCI) setBoard(b Board, history History) {
+	b.Chess960 = u.chess960
+
+	u.moveListMtx.Lock()
+	u.board = b
+	u.history = history
+	u.gameMoveCount = b.FullMove
+	u.gameActiveColor = b.ActiveColor.String()
+	u.moveListMtx.Unlock()
 
-	b := FENtoBoard(u.fen)
-	b.Moves(moves...)
 	u.fen = b.FEN()
-	u.gameMoveCount = atoi(b.FullMove)
-	u.gameActiveColor = b.ActiveColor
 
-	u.WriteLine(fmt.Sprintf("info fen set to '%s' move %d, %s to play", u.fen, u.gameMoveCount, u.gameActiveColor))
+	u.reportOpening(history)
+}
+
+// reportOpening writes the opening's ECO code and name when the game reaches a
+// newly named position.
+func (u *UCI) reportOpening(history History) {
+	if u.chess960 {
+		return
+	}
+
+	o, ok := ClassifyOpening(history)
+	if !ok || o == u.opening {
+		return
+	}
+
+	u.opening = o
+	u.WriteLine(fmt.Sprintf("info string opening %s", o))
+}
+
+// recordPosition records the position sent by the GUI for the game's PGN.
+func (u *UCI) recordPosition(start Board, moves []string) {
+	u.moveListMtx.Lock()
+	u.game.setPosition(start, moves)
+	u.moveListMtx.Unlock()
+}
+
+// recordBookMove records a move played from the book for the game's PGN.
+func (u *UCI) recordBookMove(move string) {
+	u.moveListMtx.Lock()
+	if !u.board.empty() {
+		u.game.addBookMove(&u.board, move)
+	}
+	u.moveListMtx.Unlock()
 }
 
 func (u *UCI) printMoveList(lock bool) {
@@ -782,7 +1183,7 @@ func (u *UCI) WriteLine(s string) {
 	u.mtxStdout.Lock()
 	defer u.mtxStdout.Unlock()
 	u.logInfo(fmt.Sprintf("<- %s", s))
-	_, _ = fmt.Fprintln(os.Stdout, s)
+	_, _ = fmt.Fprintln(u.out, s)
 }
 
 func (u *UCI) WriteLines(v ...string) {
@@ -797,7 +1198,7 @@ func (u *UCI) WriteLines(v ...string) {
 
 	u.mtxStdout.Lock()
 	defer u.mtxStdout.Unlock()
-	_, _ = fmt.Fprint(os.Stdout, s)
+	_, _ = fmt.Fprint(u.out, s)
 }
 
 func ts() string {
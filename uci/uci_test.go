@@ -0,0 +1,224 @@
+package uci
+
+import (
+	"strings"
+	"testing"
+)
+
+// ruyLopez is out of the book, so 'go' is always a search
+const ruyLopez = "r1bqkbnr/1ppp1ppp/p1n5/1B2p3/4P3/5N2/PPPP1PPP/RNBQK2R w KQkq - 0 4"
+
+const timedGo = "go wtime 300000 btime 300000 winc 0 binc 0"
+
+func TestProxyUCI(t *testing.T) {
+	// arrange
+	e := newFakeEngine()
+	u, out := newFakeProxy(t, e)
+
+	// act
+	u.parseLine("uci")
+	line := waitLine(t, out, "uciok")
+
+	// assert
+	if line != "uciok" {
+		t.Errorf("want 'uciok' got: '%s'", line)
+	}
+	if got := e.sent("setoption name MultiPV"); len(got) != 1 || got[0] != "setoption name MultiPV value 5" {
+		t.Errorf("want MultiPV set to 5 got: %v", got)
+	}
+}
+
+func TestProxyBestMove(t *testing.T) {
+	// arrange
+	cases := []struct {
+		name       string
+		agro       bool
+		transcript []string
+		want       string
+	}{
+		{
+			name: "equality",
+			transcript: []string{
+				"info depth 12 seldepth 16 multipv 1 score cp 120 nodes 1000 nps 100000 time 10 pv b5a4 g8f6",
+				"info depth 12 seldepth 15 multipv 2 score cp 10 nodes 1000 nps 100000 time 10 pv b5c6 d7c6",
+				"info depth 12 seldepth 14 multipv 3 score cp -40 nodes 1000 nps 100000 time 10 pv b5c4 g8f6",
+				"bestmove b5a4 ponder g8f6",
+			},
+			want: "bestmove b5c6 eval 0.10 agro false",
+		},
+		{
+			name: "no blunders",
+			transcript: []string{
+				"info depth 12 seldepth 16 multipv 1 score cp 30 nodes 1000 nps 100000 time 10 pv b5a4 g8f6",
+				"info depth 12 seldepth 15 multipv 2 score cp -300 nodes 1000 nps 100000 time 10 pv b5d3 d7d5",
+				"bestmove b5a4 ponder g8f6",
+			},
+			want: "bestmove b5a4 ponder g8f6 eval 0.30 agro false",
+		},
+		{
+			name: "winning turns agro",
+			transcript: []string{
+				"info depth 12 seldepth 16 multipv 1 score mate 3 nodes 1000 nps 100000 time 10 pv b5a4 g8f6",
+				"info depth 12 seldepth 15 multipv 2 score cp 10 nodes 1000 nps 100000 time 10 pv b5c6 d7c6",
+				"bestmove b5a4 ponder g8f6",
+			},
+			want: "bestmove b5a4 ponder g8f6 eval M3 agro true",
+		},
+		{
+			name: "agro",
+			agro: true,
+			transcript: []string{
+				"info depth 12 seldepth 16 multipv 1 score cp 120 nodes 1000 nps 100000 time 10 pv b5a4 g8f6",
+				"info depth 12 seldepth 15 multipv 2 score cp 10 nodes 1000 nps 100000 time 10 pv b5c6 d7c6",
+				"bestmove b5a4 ponder g8f6",
+			},
+			want: "bestmove b5a4 ponder g8f6 eval 1.20 agro true",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			e := newFakeEngine(c.transcript)
+			u, out := newFakeProxy(t, e)
+			u.parseLine("position fen " + ruyLopez)
+			u.gameAgro = c.agro
+
+			// act
+			u.parseLine(timedGo)
+			got := waitLine(t, out, "bestmove ")
+
+			// assert
+			if got != c.want {
+				t.Errorf("want '%s' got: '%s'", c.want, got)
+			}
+			if n := len(e.sent("go ")); n != 1 {
+				t.Errorf("want 1 search got: %d", n)
+			}
+		})
+	}
+}
+
+func TestProxyBookMove(t *testing.T) {
+	// arrange
+	cases := []struct {
+		name     string
+		eval     string
+		stop     bool
+		rejected bool
+	}{
+		{name: "accepted", eval: "cp 15"},
+		{name: "at the floor", eval: "cp -300"},
+		{name: "below the floor", eval: "cp -301", rejected: true},
+		{name: "getting mated", eval: "mate -5", rejected: true},
+		{name: "stopped below the floor", eval: "cp -500", stop: true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			check := []string{
+				"info depth 7 seldepth 9 multipv 1 score cp 40 nodes 300 nps 100000 time 1 pv a2a3 e7e5",
+				"info depth 8 seldepth 10 multipv 1 score " + c.eval + " nodes 500 nps 100000 time 15 pv a2a3 e7e5",
+			}
+			if c.stop {
+				check = append(check, "stop")
+			}
+			check = append(check, "bestmove a2a3 ponder e7e5")
+
+			e := newFakeEngine(
+				check,
+				[]string{
+					"info depth 12 seldepth 16 multipv 1 score cp 25 nodes 1000 nps 100000 time 20 pv d2d4 d7d5",
+					"bestmove d2d4 ponder d7d5",
+				},
+			)
+			u, out := newFakeProxy(t, e)
+			u.parseLine("position startpos")
+
+			// act
+			u.parseLine(timedGo)
+			if c.stop {
+				u.parseLine("stop")
+			}
+			lines := waitLines(t, out, "bestmove ")
+			got := lines[len(lines)-1]
+
+			// assert
+			checks := e.sent("go movetime 250 searchmoves ")
+			if len(checks) != 1 {
+				t.Fatalf("want 1 book move check got: %v", checks)
+			}
+			bookMove := strings.TrimPrefix(checks[0], "go movetime 250 searchmoves ")
+
+			want := "bestmove " + bookMove
+			searches := 1
+			if c.rejected {
+				want = "bestmove d2d4 ponder d7d5 eval 0.25 agro false"
+				searches = 2
+			}
+			if got != want {
+				t.Errorf("want '%s' got: '%s'", want, got)
+			}
+			if n := len(e.sent("go ")); n != searches {
+				t.Errorf("want %d searches got: %d", searches, n)
+			}
+			for _, line := range lines {
+				if strings.HasPrefix(line, "info ") && strings.HasSuffix(line, " pv a2a3 e7e5") {
+					t.Errorf("want the book move check's info hidden got: '%s'", line)
+				}
+			}
+		})
+	}
+}
+
+func TestProxyGameOver(t *testing.T) {
+	// arrange
+	cases := []struct {
+		name       string
+		position   string
+		transcript []string
+		want       []string
+		searches   int
+	}{
+		{
+			name:     "checkmate",
+			position: "fen rnb1kbnr/pppp1ppp/8/4p3/6Pq/5P2/PPPPP2P/RNBQKBNR w KQkq - 1 3",
+			want:     []string{"info string game over: checkmate", "bestmove (none)"},
+		},
+		{
+			name:     "stalemate",
+			position: "fen 7k/5Q2/6K1/8/8/8/8/8 b - - 0 1",
+			want:     []string{"info string game over: stalemate", "bestmove (none)"},
+		},
+		{
+			name:     "threefold repetition is played on",
+			position: "fen " + ruyLopez + " moves b5a4 g8f6 a4b5 f6g8 b5a4 g8f6 a4b5 f6g8",
+			transcript: []string{
+				"info depth 12 seldepth 16 multipv 1 score cp 10 nodes 1000 nps 100000 time 10 pv b5a4 g8f6",
+				"bestmove b5a4 ponder g8f6",
+			},
+			want:     []string{"info string draw can be claimed: threefold repetition", "bestmove b5a4 ponder g8f6 eval 0.10 agro false"},
+			searches: 1,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			e := newFakeEngine(c.transcript)
+			u, out := newFakeProxy(t, e)
+			u.parseLine("position " + c.position)
+
+			// act
+			u.parseLine(timedGo)
+
+			// assert
+			for _, want := range c.want {
+				if got := waitLine(t, out, want); got != want {
+					t.Errorf("want '%s' got: '%s'", want, got)
+				}
+			}
+			if got := e.sent("go "); len(got) != c.searches {
+				t.Errorf("want %d searches got: %v", c.searches, got)
+			}
+		})
+	}
+}
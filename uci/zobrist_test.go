@@ -0,0 +1,121 @@
+package uci
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestKey(t *testing.T) {
+	// arrange
+	// test vectors from http://hgm.nubati.net/book_format.html
+	cases := []struct {
+		moves string
+		fen   string
+		want  uint64
+	}{
+		{
+			moves: "",
+			fen:   "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1",
+			want:  0x463b96181691fc9c,
+		},
+		{
+			moves: "e2e4",
+			fen:   "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1",
+			want:  0x823c9b50fd114196,
+		},
+		{
+			moves: "e2e4 d7d5",
+			fen:   "rnbqkbnr/ppp1pppp/8/3p4/4P3/8/PPPP1PPP/RNBQKBNR w KQkq d6 0 2",
+			want:  0x0756b94461c50fb0,
+		},
+		{
+			moves: "e2e4 d7d5 e4e5",
+			fen:   "rnbqkbnr/ppp1pppp/8/3pP3/8/8/PPPP1PPP/RNBQKBNR b KQkq - 0 2",
+			want:  0x662fafb965db29d4,
+		},
+		{
+			moves: "e2e4 d7d5 e4e5 f7f5",
+			fen:   "rnbqkbnr/ppp1p1pp/8/3pPp2/8/8/PPPP1PPP/RNBQKBNR w KQkq f6 0 3",
+			want:  0x22a48b5a8e47ff78,
+		},
+		{
+			moves: "e2e4 d7d5 e4e5 f7f5 e1e2",
+			fen:   "rnbqkbnr/ppp1p1pp/8/3pPp2/8/8/PPPPKPPP/RNBQ1BNR b kq - 1 3",
+			want:  0x652a607ca3f242c1,
+		},
+		{
+			moves: "e2e4 d7d5 e4e5 f7f5 e1e2 e8f7",
+			fen:   "rnbq1bnr/ppp1pkpp/8/3pPp2/8/8/PPPPKPPP/RNBQ1BNR w - - 2 4",
+			want:  0x00fdd303c946bdd9,
+		},
+		{
+			moves: "a2a4 b7b5 h2h4 b5b4 c2c4",
+			fen:   "rnbqkbnr/p1pppppp/8/8/PpP4P/8/1P1PPPP1/RNBQKBNR b KQkq c3 0 3",
+			want:  0x3c8123ea7b067637,
+		},
+		{
+			moves: "a2a4 b7b5 h2h4 b5b4 c2c4 b4c3 a1a3",
+			fen:   "rnbqkbnr/p1pppppp/8/8/P6P/R1p5/1P1PPPP1/1NBQKBNR b Kkq - 1 4",
+			want:  0x5c3f9b829b279560,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.fen, func(t *testing.T) {
+			// act
+			fromFEN := FENtoBoard(c.fen)
+			fromMoves := FENtoBoard(startPosFEN)
+			if c.moves != "" {
+				fromMoves.Moves(strings.Split(c.moves, " ")...)
+			}
+
+			// assert
+			if got := fromFEN.Key(); got != c.want {
+				t.Errorf("from FEN, want: %016x got: %016x", c.want, got)
+			}
+			if got := fromMoves.Key(); got != c.want {
+				t.Errorf("from moves, want: %016x got: %016x", c.want, got)
+			}
+			if got := fromMoves.FEN(); got != c.fen {
+				t.Errorf("\nwant: '%s'\ngot:  '%s'", c.fen, got)
+			}
+		})
+	}
+}
+
+func TestKeyIncremental(t *testing.T) {
+	// arrange
+	r := rand.New(rand.NewSource(1))
+	fens := []string{
+		startPosFEN,
+		"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1",
+		"r3k2r/Pppp1ppp/1b3nbN/nP6/BBP1P3/q4N2/Pp1P2PP/R2Q1RK1 w kq - 0 1",
+	}
+
+	for _, fen := range fens {
+		for game := 0; game < 20; game++ {
+			t.Run(fmt.Sprintf("%s game %d", fen, game), func(t *testing.T) {
+				b := FENtoBoard(fen)
+				for ply := 0; ply < 100; ply++ {
+					moves := b.LegalMoves()
+					if len(moves) == 0 {
+						break
+					}
+
+					// act
+					move := moves[r.Intn(len(moves))]
+					b.Moves(move)
+
+					// assert
+					fromFEN := FENtoBoard(b.FEN())
+					want := fromFEN.Key()
+					if got := b.Key(); got != want {
+						t.Fatalf("after %s, want: %016x got: %016x (%s)", move, want, got, b.FEN())
+					}
+				}
+			})
+		}
+	}
+}